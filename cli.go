@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// version is set at build time: go build -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+// Environment variables overriding config fields start with this prefix
+const envPrefix = "CRAWLER_"
+
+// command is a crawler subcommand
+type command struct {
+	name     string
+	args     string // positional arguments
+	summary  string
+	noConfig bool // whether the command does without crawl configuration
+	run      func(cfg *Config, args []string) int
+}
+
+var commands = []command{
+	{name: "crawl", args: "[config file]", summary: "Crawl pages starting from the seed URL, the default command", run: crawl},
+	{name: "explain", args: "<url>", summary: "Show step by step how the crawler decides on the URL", run: explain},
+	{name: "validate-config", summary: "Check configuration and print effective settings as JSON", run: validateConfig},
+	{name: "worker", args: "<coordinator URL>", summary: "Fetch and parse pages for a crawl run with distributed.coordinator_address", noConfig: true, run: worker},
+	{name: "search", args: "<index file> <query>", summary: "Search full-text index saved by a crawl", noConfig: true, run: search},
+	{name: "version", summary: "Print version", noConfig: true, run: printVersion},
+}
+
+// run executes the command given in arguments, returns exit code
+func run(args []string) int {
+	cmd := commands[0]
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			printUsage(os.Stdout)
+			return 0
+		}
+		for i := range commands {
+			if commands[i].name == args[0] {
+				cmd, args = commands[i], args[1:]
+				break
+			}
+		}
+	}
+	if cmd.noConfig {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+			printCommandUsage(os.Stdout, cmd, nil)
+			return 0
+		}
+		return cmd.run(nil, args)
+	}
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configFile := flags.String("config", "", "")
+	var settings []optionSetting
+	options := configOptions()
+	for i := range options {
+		flags.Var(optionFlag{option: options[i], settings: &settings}, options[i].flag, options[i].usage)
+	}
+	args, err := parseArgs(flags, args)
+	if errors.Is(err, flag.ErrHelp) {
+		printCommandUsage(os.Stdout, cmd, options)
+		return 0
+	} else if err != nil {
+		slog.Error("Invalid arguments", "error", err, "help", "crawler "+cmd.name+" --help")
+		return 2
+	}
+	if cmd.name == "crawl" && len(args) == 1 && *configFile == "" {
+		// Config file may be given as the only argument
+		*configFile, args = args[0], nil
+	}
+	cfg, err := loadConfig(*configFile, settings)
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		return 1
+	}
+	slog.SetDefault(cfg.Log.build(os.Stderr, cfg.Debug))
+	return cmd.run(cfg, args)
+}
+
+// parseArgs parses flags given before, after or between positional arguments, returns the latter
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flags.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			// Everything after "--" is positional
+			return append(positional, rest...), nil
+		}
+		positional, args = append(positional, rest[0]), rest[1:]
+	}
+}
+
+// configOption is a Config field settable with a command line flag or an environment variable
+type configOption struct {
+	flag  string       // flag name, e.g. "max-pages" or "scope-hosts"
+	env   string       // environment variable name, e.g. "CRAWLER_MAX_PAGES"
+	usage string       // option description
+	index []int        // field index in Config
+	typ   reflect.Type // field type
+}
+
+// configOptions lists options for all Config fields, named after their JSON keys
+func configOptions() []configOption {
+	return structOptions(reflect.TypeOf(Config{}), nil, "", envPrefix)
+}
+
+// structOptions lists options for struct fields, nested structs are flattened with their key as a prefix
+func structOptions(t reflect.Type, index []int, flagPrefix, envPrefix string) []configOption {
+	var options []configOption
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		flagName := flagPrefix + strings.ReplaceAll(key, "_", "-")
+		envName := envPrefix + strings.ToUpper(key)
+		if field.Type.Kind() == reflect.Struct {
+			options = append(options, structOptions(field.Type, fieldIndex, flagName+"-", envName+"_")...)
+			continue
+		}
+		options = append(options, configOption{
+			flag:  flagName,
+			env:   envName,
+			usage: field.Tag.Get("usage"),
+			index: fieldIndex,
+			typ:   field.Type,
+		})
+	}
+	return options
+}
+
+// set parses the value into the config field
+func (o configOption) set(cfg *Config, value string) error {
+	return setValue(reflect.ValueOf(cfg).Elem().FieldByIndex(o.index), value)
+}
+
+// typeName returns value placeholder for help, empty for boolean flags
+func (o configOption) typeName() string {
+	switch o.typ.Kind() {
+	case reflect.Bool:
+		return ""
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int64:
+		return "int"
+	case reflect.Uint, reflect.Uint64:
+		return "uint"
+	case reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		if isScalarList(o.typ) {
+			return "list"
+		}
+	}
+	return "json"
+}
+
+// setValue parses string into the value: lists are comma-separated, anything else complex is JSON
+func setValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		if isScalarList(v.Type()) {
+			return setList(v, s)
+		}
+		// Decoding into a fresh value replaces maps and lists instead of merging
+		fresh := reflect.New(v.Type())
+		decoder := json.NewDecoder(strings.NewReader(s))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(fresh.Interface()); err != nil {
+			return err
+		}
+		v.Set(fresh.Elem())
+	}
+	return nil
+}
+
+// setList parses comma-separated list into the slice value
+func setList(v reflect.Value, s string) error {
+	list := reflect.MakeSlice(v.Type(), 0, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := setValue(elem, item); err != nil {
+			return err
+		}
+		list = reflect.Append(list, elem)
+	}
+	v.Set(list)
+	return nil
+}
+
+// isScalarList tells if the type is a slice of strings or numbers
+func isScalarList(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	switch t.Elem().Kind() {
+	case reflect.String, reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// applyEnv overrides config fields with non-empty environment variables, reporting all bad values at once
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	var errs []error
+	for _, o := range configOptions() {
+		if value, ok := lookup(o.env); ok && value != "" {
+			if err := o.set(cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid value %q for %s: %w", value, o.env, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// optionSetting is a config option value given on the command line
+type optionSetting struct {
+	option configOption
+	value  string
+}
+
+// optionFlag collects option values to apply them on top of config file and environment
+type optionFlag struct {
+	option   configOption
+	settings *[]optionSetting
+}
+
+func (f optionFlag) String() string {
+	return ""
+}
+
+func (f optionFlag) Set(value string) error {
+	// Parsing into a scratch config reports bad values right away
+	if err := f.option.set(&Config{}, value); err != nil {
+		return err
+	}
+	*f.settings = append(*f.settings, optionSetting{option: f.option, value: value})
+	return nil
+}
+
+func (f optionFlag) IsBoolFlag() bool {
+	return f.option.typ.Kind() == reflect.Bool
+}
+
+// printUsage prints the list of commands
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "Usage: crawler [command] [options] [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	_, _ = fmt.Fprintf(w, "\nRun 'crawler <command> --help' for command options.\n")
+}
+
+// printCommandUsage prints command arguments and options
+func printCommandUsage(w io.Writer, cmd command, options []configOption) {
+	_, _ = fmt.Fprintf(w, "Usage: crawler %s", cmd.name)
+	if !cmd.noConfig {
+		_, _ = fmt.Fprint(w, " [options]")
+	}
+	if cmd.args != "" {
+		_, _ = fmt.Fprintf(w, " %s", cmd.args)
+	}
+	_, _ = fmt.Fprintf(w, "\n\n%s\n", cmd.summary)
+	if cmd.noConfig {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "\nOptions override environment variables, which override the config file:\n")
+	_, _ = fmt.Fprintf(w, "  --config string\n    \tJSON, YAML or TOML config file, %s if exists [%s]\n", defaultConfigFile, configFileEnv)
+	defaults := reflect.ValueOf(defaultConfig())
+	for _, o := range options {
+		name, usage := o.flag, o.usage
+		if t := o.typeName(); t != "" {
+			name += " " + t
+		}
+		if value := defaults.FieldByIndex(o.index); !value.IsZero() {
+			usage += fmt.Sprintf(" (default %v)", value.Interface())
+		}
+		_, _ = fmt.Fprintf(w, "  --%s\n    \t%s [%s]\n", name, usage, o.env)
+	}
+	_, _ = fmt.Fprintf(w, "\nLists are comma-separated, json values take the same form as in the config file.\n")
+}
+
+// validateConfig checks configuration and prints effective settings, secrets redacted
+func validateConfig(cfg *Config, args []string) int {
+	if len(args) > 0 {
+		slog.Error("Unexpected arguments", "args", strings.Join(args, " "))
+		return 2
+	}
+	if _, err := buildCrawler(cfg, slog.Default(), nil, nil); err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		return 1
+	}
+	printConfig(os.Stdout, cfg)
+	return 0
+}
+
+// printConfig prints effective settings as JSON, tokens, header values and URL passwords redacted
+func printConfig(w io.Writer, cfg *Config) {
+	// Config contains only JSON-compatible values
+	effective, _ := json.MarshalIndent(cfg.redacted(), "", "  ")
+	_, _ = fmt.Fprintln(w, string(effective))
+}
+
+// printVersion prints build version and revision
+func printVersion(_ *Config, _ []string) int {
+	fmt.Printf("crawler %s", version)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				fmt.Printf(" (%s)", setting.Value)
+			}
+		}
+		fmt.Printf(" %s", info.GoVersion)
+	}
+	fmt.Println()
+	return 0
+}
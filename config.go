@@ -0,0 +1,630 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/distributed"
+	"github.com/dmitry-vovk/wcrawler/crawler/output_sink"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_parser"
+	"github.com/dmitry-vovk/wcrawler/crawler/types"
+	"github.com/dmitry-vovk/wcrawler/crawler/url_filter"
+	"github.com/dmitry-vovk/wcrawler/crawler/visited_set"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultConfigFile = "config.json"
+	configFileEnv     = "CRAWLER_CONFIG"
+	// Supported config file formats
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatTOML = "toml"
+	// Supported log formats
+	logFormatText = "text"
+	logFormatJSON = "json"
+	// Supported visited links stores
+	visitedStoreMemory = "memory"
+	visitedStoreBloom  = "bloom"
+	visitedStoreDisk   = "disk"
+	// Default settings not defined by crawler package
+	defaultUserAgent = "wcrawler"
+	defaultMaxPages  = 100
+	// Upper limit of max_parallel_requests
+	maxParallelRequests = 100
+)
+
+// Config contains all the variables needed for crawler
+type Config struct {
+	SeedURL                 string              `json:"seed_url" usage:"Starting URL"`
+	AllowWWWPrefix          bool                `json:"allow_www_prefix" usage:"Treat example.com and www.example.com as the same host"`
+	IgnoreRobotsTxt         bool                `json:"ignore_robots_txt" usage:"Do not obey robots.txt rules"`
+	IgnoreRobotsMeta        bool                `json:"ignore_robots_meta" usage:"Follow links regardless of nofollow robots meta tags, X-Robots-Tag headers and rel='nofollow'"`
+	DoHeadRequests          bool                `json:"do_head_requests" usage:"Do HEAD requests before GET requests to avoid fetching inappropriate links"`
+	UserAgent               string              `json:"user_agent" usage:"HTTP user agent string to use"`
+	MaxPages                uint64              `json:"max_pages" usage:"Do not crawl more than this number of pages"`
+	MaxParallelRequests     uint                `json:"max_parallel_requests" usage:"How many requests to allow to run in parallel"`
+	MaxBodySize             int64               `json:"max_body_size" usage:"Maximum response body size in bytes, 0 for no limit"`
+	TruncateLargeBodies     bool                `json:"truncate_large_bodies" usage:"Truncate bodies over max_body_size instead of skipping the page"`
+	MinTransferRate         int64               `json:"min_transfer_rate" usage:"Abort responses transferred slower than this number of bytes per second, 0 for no limit"`
+	LinkSources             []string            `json:"link_sources" usage:"Elements to collect links from and follow, e.g. 'a[href]', 'iframe[src]'; only 'a[href]' if empty"`
+	SkipCanonicalDuplicates bool                `json:"skip_canonical_duplicates" usage:"Do not follow links from pages whose canonical URL has been already seen, queue the canonical URL instead"`
+	CanonicalReport         bool                `json:"canonical_report" usage:"Print canonical clusters, chains, loops and cross-domain canonicals after the crawl, not supported with 'bloom' and 'disk' visited stores"`
+	NearDuplicateDistance   int                 `json:"near_duplicate_distance" usage:"Maximum Hamming distance between SimHashes of near-duplicate pages, -1 to disable detection, required with 'bloom' and 'disk' visited stores"`
+	SkipNearDuplicateLinks  bool                `json:"skip_near_duplicate_links" usage:"Do not follow links from pages detected as near-duplicates"`
+	DuplicateReport         bool                `json:"duplicate_report" usage:"Print clusters of near-duplicate pages after the crawl"`
+	IndexPath               string              `json:"index_path" usage:"File to save full-text search index of crawled pages to, no index is built if empty"`
+	PrintMetadata           bool                `json:"print_metadata" usage:"Print page metadata (title, description, headings, etc.) along with links, in text and jsonl output"`
+	Output                  OutputConfig        `json:"output" usage:"Where and in which format to write page results"`
+	MetricsAddress          string              `json:"metrics_address" usage:"Address to serve Prometheus metrics on at /metrics during the crawl, e.g. 'localhost:9090', disabled if empty"`
+	ControlAddress          string              `json:"control_address" usage:"Address to serve HTTP API controlling the crawl on, e.g. 'localhost:9091', disabled if empty"`
+	ControlToken            string              `json:"control_token" usage:"Bearer token required by the control API, mandatory unless control_address is a loopback address"`
+	Progress                bool                `json:"progress" usage:"Show pages done, rates, errors and ETA at the bottom of the terminal, ignored if stderr is not a terminal"`
+	Distributed             DistributedConfig   `json:"distributed" usage:"Fetching pages by worker processes instead of the crawl process"`
+	Visited                 VisitedConfig       `json:"visited" usage:"Where visited links are remembered"`
+	Scope                   ScopeConfig         `json:"scope" usage:"Which hosts, schemes and ports belong to the crawl"`
+	Normalisation           NormalisationConfig `json:"normalisation" usage:"How links are normalised before filtering and deduplication"`
+	URLRules                []URLRuleConfig     `json:"url_rules" usage:"Ordered include/exclude URL rules, the first matching rule decides"`
+	Headers                 []HeaderRuleConfig  `json:"headers" usage:"Extra request headers, rules matching a request are applied in list order, later ones override earlier ones"`
+	Log                     LogConfig           `json:"log" usage:"How and how much to log"`
+	Debug                   bool                `json:"debug" usage:"Log extra details, same as log.level 'debug'"`
+}
+
+// ScopeConfig describes crawl scope policies
+type ScopeConfig struct {
+	Hosts        string   `json:"hosts" usage:"'exact', 'www' or 'subdomains' of the seed URL host; 'www' or 'exact' depending on allow_www_prefix if empty"`
+	AllowedHosts []string `json:"allowed_hosts" usage:"Extra hosts to crawl, '*.example.com' matches all subdomains of example.com"`
+	Schemes      string   `json:"schemes" usage:"'any' (default), 'https_only' or 'http_to_https'"`
+	Ports        []int    `json:"ports" usage:"Allowed ports, 80 and 443 stand for links without explicit port; any port if empty"`
+}
+
+// build converts scope config into filter scope for the seed host
+func (s ScopeConfig) build(seedHost string, allowWWWPrefix bool) (url_filter.Scope, error) {
+	scope := url_filter.Scope{
+		BaseDomain:   seedHost,
+		Hosts:        url_filter.HostPolicy(s.Hosts),
+		AllowedHosts: s.AllowedHosts,
+		Schemes:      url_filter.SchemePolicy(s.Schemes),
+		Ports:        s.Ports,
+	}
+	switch scope.Hosts {
+	case "":
+		scope.Hosts = url_filter.HostExact
+		if allowWWWPrefix {
+			scope.Hosts = url_filter.HostWWW
+		}
+	case url_filter.HostExact, url_filter.HostWWW, url_filter.HostSubdomains:
+	default:
+		return scope, fmt.Errorf("unknown hosts policy %q", s.Hosts)
+	}
+	switch scope.Schemes {
+	case "", url_filter.SchemeAny, url_filter.SchemeHTTPSOnly, url_filter.SchemeHTTPToHTTPS:
+	default:
+		return scope, fmt.Errorf("unknown schemes policy %q", s.Schemes)
+	}
+	return scope, nil
+}
+
+// OutputConfig describes page results output
+type OutputConfig struct {
+	Format  string `json:"format" usage:"'text', 'jsonl' (JSON Lines), 'csv' or 'sqlite' (database file, runs are added to existing ones)"`
+	Path    string `json:"path" usage:"File to write results to, stdout if empty"`
+	MaxSize int64  `json:"max_size" usage:"Start a new file once this number of bytes is written, older files are numbered; 0 for no rotation"`
+	Gzip    bool   `json:"gzip" usage:"Compress output files with gzip"`
+}
+
+// build opens results output, writing to stdout if no path is set; the run is recorded in SQLite databases
+func (o OutputConfig) build(printMetadata bool, run output_sink.Run, stdout io.Writer) (output_sink.OutputSink, error) {
+	if o.Format == output_sink.FormatSQLite {
+		return output_sink.OpenSQLite(o.Path, run)
+	}
+	w, err := output_sink.Open(o.Path, o.MaxSize, o.Gzip, stdout)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := output_sink.New(o.Format, w, printMetadata)
+	if err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	return sink, nil
+}
+
+// DistributedConfig describes coordinator/worker mode
+type DistributedConfig struct {
+	CoordinatorAddress string `json:"coordinator_address" usage:"Address to serve worker API on, e.g. '0.0.0.0:9092', loopback interface if the host is omitted; pages are then fetched by 'crawler worker' processes, disabled if empty"`
+	Token              string `json:"token" usage:"Bearer token workers must send, mandatory unless coordinator_address is a loopback address; workers read it from CRAWLER_DISTRIBUTED_TOKEN"`
+	LeaseTimeout       int    `json:"lease_timeout" usage:"Seconds a worker has to report a page before the job is handed out to another worker"`
+	WorkerBatchSize    int    `json:"worker_batch_size" usage:"Jobs a worker leases and processes in parallel at once"`
+}
+
+// VisitedConfig describes visited links store
+type VisitedConfig struct {
+	Store             string  `json:"store" usage:"'memory' (exact, grows with the crawl), 'bloom' (a few bytes per link, may skip some unvisited links) or 'disk' (exact, SQLite file)"`
+	FalsePositiveRate float64 `json:"false_positive_rate" usage:"Share of unvisited links 'bloom' store may take for visited ones"`
+	Path              string  `json:"path" usage:"File of 'disk' store, cleared before the crawl; a temporary file if empty"`
+}
+
+// build returns visited links store, the disk one is to be closed after the crawl
+func (v VisitedConfig) build() (types.VisitedSet, error) {
+	switch v.Store {
+	case visitedStoreBloom:
+		return visited_set.NewBloom(v.FalsePositiveRate), nil
+	case visitedStoreDisk:
+		return visited_set.OpenDisk(v.Path)
+	}
+	return visited_set.NewMap(), nil
+}
+
+// buildCanonicals returns store of seen canonical URLs of the same kind, the disk one is kept next to visited links
+func (v VisitedConfig) buildCanonicals() (types.VisitedSet, error) {
+	if v.Path != "" {
+		v.Path += ".canonicals"
+	}
+	return v.build()
+}
+
+// LogConfig describes logging
+type LogConfig struct {
+	Format string `json:"format" usage:"'text' (key=value pairs) or 'json'"`
+	Level  string `json:"level" usage:"'debug' (every link decision), 'info', 'warn' (robots.txt denials and the like) or 'error' (failed pages)"`
+}
+
+// build returns logger writing to w, debug lowers the level to debug
+func (l LogConfig) build(w io.Writer, debug bool) *slog.Logger {
+	var level slog.Level
+	if l.Level != "" {
+		// Level is checked by validation
+		_ = level.UnmarshalText([]byte(l.Level))
+	}
+	if debug {
+		level = slog.LevelDebug
+	}
+	options := &slog.HandlerOptions{Level: level}
+	if l.Format == logFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+// NormalisationConfig describes link normalisation policy
+type NormalisationConfig struct {
+	StripParams   []string `json:"strip_params" usage:"Query and path (;name=value) parameters to remove, case-insensitive, trailing '*' matches any suffix"`
+	SortParams    bool     `json:"sort_params" usage:"Sort query parameters by name"`
+	LowercasePath bool     `json:"lowercase_path" usage:"Lowercase paths, for case-insensitive servers"`
+	TrailingSlash string   `json:"trailing_slash" usage:"'remove' (default), 'add' or 'keep'"`
+	CollapseIndex bool     `json:"collapse_index" usage:"Remove index.html, index.php, default.asp etc. from paths"`
+}
+
+// build converts normalisation config into filter settings
+func (n NormalisationConfig) build() (url_filter.Normalisation, error) {
+	policy := url_filter.TrailingSlashPolicy(n.TrailingSlash)
+	switch policy {
+	case "", url_filter.TrailingSlashRemove, url_filter.TrailingSlashAdd, url_filter.TrailingSlashKeep:
+	default:
+		return url_filter.Normalisation{}, fmt.Errorf("unknown trailing slash policy %q", n.TrailingSlash)
+	}
+	return url_filter.Normalisation{
+		StripParams:   n.StripParams,
+		SortParams:    n.SortParams,
+		LowercasePath: n.LowercasePath,
+		TrailingSlash: policy,
+		CollapseIndex: n.CollapseIndex,
+	}, nil
+}
+
+// URLRuleConfig describes a rule to include or exclude links, exactly one condition is expected
+type URLRuleConfig struct {
+	// "include" or "exclude"
+	Action string `json:"action"`
+	// Match links with path starting with this prefix
+	PathPrefix string `json:"path_prefix"`
+	// Match path and query against glob pattern: "**" matches anything, "*" does not match "/", "?" is literal
+	Glob string `json:"glob"`
+	// Match the whole link against regular expression
+	Regex string `json:"regex"`
+	// Match links having this query parameter
+	QueryParam string `json:"query_param"`
+}
+
+// buildURLRules converts URL rules config into filter rules
+func buildURLRules(rules []URLRuleConfig) ([]url_filter.Rule, error) {
+	urlRules := make([]url_filter.Rule, 0, len(rules))
+	for i, rule := range rules {
+		action := url_filter.RuleAction(rule.Action)
+		if action != url_filter.Include && action != url_filter.Exclude {
+			return nil, fmt.Errorf("rule #%d: unknown action %q", i+1, rule.Action)
+		}
+		var matchers []url_filter.Matcher
+		if rule.PathPrefix != "" {
+			matchers = append(matchers, url_filter.PathPrefix(rule.PathPrefix))
+		}
+		if rule.Glob != "" {
+			m, err := url_filter.Glob(rule.Glob)
+			if err != nil {
+				return nil, fmt.Errorf("rule #%d: %w", i+1, err)
+			}
+			matchers = append(matchers, m)
+		}
+		if rule.Regex != "" {
+			m, err := url_filter.Regexp(rule.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("rule #%d: %w", i+1, err)
+			}
+			matchers = append(matchers, m)
+		}
+		if rule.QueryParam != "" {
+			matchers = append(matchers, url_filter.QueryParam(rule.QueryParam))
+		}
+		if len(matchers) != 1 {
+			return nil, fmt.Errorf("rule #%d: exactly one of path_prefix, glob, regex or query_param expected", i+1)
+		}
+		urlRules = append(urlRules, url_filter.Rule{Action: action, Matcher: matchers[0]})
+	}
+	return urlRules, nil
+}
+
+// HeaderRuleConfig describes headers to add to requests matching host and/or URL pattern
+type HeaderRuleConfig struct {
+	// Limit the rule to this host
+	Host string `json:"host"`
+	// Limit the rule to URLs matching this regular expression
+	URLPattern string `json:"url_pattern"`
+	// Headers to add
+	Headers map[string]string `json:"headers"`
+}
+
+// buildLinkSources validates link sources config
+func buildLinkSources(sources []string) ([]page_parser.LinkSource, error) {
+	linkSources := make([]page_parser.LinkSource, 0, len(sources))
+next:
+	for _, source := range sources {
+		for _, known := range page_parser.AllLinkSources {
+			if source == string(known) {
+				linkSources = append(linkSources, known)
+				continue next
+			}
+		}
+		return nil, fmt.Errorf("unknown link source %q", source)
+	}
+	return linkSources, nil
+}
+
+// buildHeaderRules converts header rules config into fetcher rules
+func buildHeaderRules(rules []HeaderRuleConfig) ([]page_fetcher.HeaderRule, error) {
+	headerRules := make([]page_fetcher.HeaderRule, 0, len(rules))
+	for i := range rules {
+		rule := page_fetcher.HeaderRule{
+			Host:    rules[i].Host,
+			Headers: make(http.Header, len(rules[i].Headers)),
+		}
+		if rules[i].URLPattern != "" {
+			re, err := regexp.Compile(rules[i].URLPattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule #%d: %w", i+1, err)
+			}
+			rule.URLPattern = re
+		}
+		for name, value := range rules[i].Headers {
+			rule.Headers.Set(name, value)
+		}
+		headerRules = append(headerRules, rule)
+	}
+	return headerRules, nil
+}
+
+// defaultConfig returns settings used unless overridden by config file, environment or flags
+func defaultConfig() Config {
+	return Config{
+		UserAgent:             defaultUserAgent + "/" + version,
+		MaxPages:              defaultMaxPages,
+		MaxParallelRequests:   crawler.DefaultMaxParallelRequests,
+		NearDuplicateDistance: crawler.DefaultNearDuplicateDistance,
+		Output:                OutputConfig{Format: output_sink.FormatText},
+		Log:                   LogConfig{Format: logFormatText, Level: "info"},
+		Visited:               VisitedConfig{Store: visitedStoreMemory, FalsePositiveRate: visited_set.DefaultFalsePositiveRate},
+		Distributed: DistributedConfig{
+			LeaseTimeout:    int(distributed.DefaultLeaseTimeout / time.Second),
+			WorkerBatchSize: distributed.DefaultBatchSize,
+		},
+	}
+}
+
+// loadConfig reads config file and applies overrides from environment and command line, in this order,
+// then validates the result; the default config file is optional
+func loadConfig(configFile string, settings []optionSetting) (*Config, error) {
+	cfg := defaultConfig()
+	explicit := configFile != ""
+	if !explicit {
+		configFile = os.Getenv(configFileEnv)
+		explicit = configFile != ""
+	}
+	if !explicit {
+		configFile = defaultConfigFile
+	}
+	if err := readConfig(configFile, &cfg); err != nil && (explicit || !errors.Is(err, fs.ErrNotExist)) {
+		return nil, fmt.Errorf("reading config file %q: %w", configFile, err)
+	}
+	if err := applyEnv(&cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	for _, s := range settings {
+		// Flag values are checked when parsed
+		_ = s.option.set(&cfg, s.value)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// readConfig decodes config file into cfg, the format is told by file extension: JSON, YAML or TOML
+func readConfig(filePath string, cfg *Config) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	return decodeConfig(data, configFormat(filePath), cfg)
+}
+
+// configFormat returns config file format by its extension, JSON by default
+func configFormat(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".toml":
+		return formatTOML
+	}
+	return formatJSON
+}
+
+// decodeConfig decodes the data into cfg, keys not matching any setting are rejected
+func decodeConfig(data []byte, format string, cfg *Config) error {
+	var raw map[string]any
+	var err error
+	switch format {
+	case formatYAML:
+		err = yaml.Unmarshal(data, &raw)
+	case formatTOML:
+		err = toml.Unmarshal(data, &raw)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	}
+	if err != nil {
+		return err
+	}
+	if errs := unknownKeys(reflect.TypeOf(*cfg), raw, ""); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	// Whatever the format is, values are decoded as JSON, so json tags define the keys
+	normalised, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(normalised, cfg)
+}
+
+// unknownKeys lists keys not matching any struct field, nested keys are prefixed with their parent key
+func unknownKeys(t reflect.Type, raw map[string]any, prefix string) []error {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]; key != "" && key != "-" {
+			fields[key] = t.Field(i).Type
+		}
+	}
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, key := range keys {
+		fieldType, ok := fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s%s: unknown key", prefix, key))
+			continue
+		}
+		switch {
+		case fieldType.Kind() == reflect.Struct:
+			if m, ok := raw[key].(map[string]any); ok {
+				errs = append(errs, unknownKeys(fieldType, m, prefix+key+".")...)
+			}
+		case fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Struct:
+			list, _ := raw[key].([]any)
+			for i := range list {
+				if m, ok := list[i].(map[string]any); ok {
+					errs = append(errs, unknownKeys(fieldType.Elem(), m, fmt.Sprintf("%s%s[%d].", prefix, key, i))...)
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// loopbackAddress tells if the host:port address is reachable from this machine only
+func loopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// loopbackDefault binds host:port address without a host to the loopback interface
+func loopbackDefault(addr string) string {
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return addr
+}
+
+// Replaces secret values in configs stored along with results
+const redactedValue = "REDACTED"
+
+// redacted returns a copy of the config safe to store: header values, tokens and URL passwords are replaced
+func (c *Config) redacted() Config {
+	r := *c
+	if r.ControlToken != "" {
+		r.ControlToken = redactedValue
+	}
+	if r.Distributed.Token != "" {
+		r.Distributed.Token = redactedValue
+	}
+	if u, err := url.Parse(c.SeedURL); err == nil {
+		r.SeedURL = u.Redacted()
+	}
+	r.Headers = make([]HeaderRuleConfig, len(c.Headers))
+	for i, rule := range c.Headers {
+		r.Headers[i] = rule
+		r.Headers[i].Headers = make(map[string]string, len(rule.Headers))
+		for name := range rule.Headers {
+			r.Headers[i].Headers[name] = redactedValue
+		}
+	}
+	return r
+}
+
+// workerSettings returns the settings workers need to fetch and parse pages the way the crawl process would;
+// crawl limits, scope, rules, paths, addresses and tokens stay with the coordinator
+func (c *Config) workerSettings() Config {
+	w := defaultConfig()
+	w.SeedURL = c.SeedURL
+	w.UserAgent = c.UserAgent
+	w.DoHeadRequests = c.DoHeadRequests
+	// Links are checked against robots.txt by the coordinator
+	w.IgnoreRobotsTxt = true
+	w.IgnoreRobotsMeta = c.IgnoreRobotsMeta
+	w.MaxBodySize = c.MaxBodySize
+	w.TruncateLargeBodies = c.TruncateLargeBodies
+	w.MinTransferRate = c.MinTransferRate
+	w.LinkSources = c.LinkSources
+	w.Headers = c.Headers
+	w.Log = c.Log
+	w.Debug = c.Debug
+	w.Distributed.WorkerBatchSize = c.Distributed.WorkerBatchSize
+	return w
+}
+
+// validate checks settings, all problems are reported at once
+func (c *Config) validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(key+": "+format, args...))
+		}
+	}
+	var seedHost string
+	if u, err := url.Parse(c.SeedURL); c.SeedURL == "" {
+		check(false, "seed_url", "required")
+	} else if err != nil {
+		check(false, "seed_url", "%s", err)
+	} else {
+		check(u.Scheme == "http" || u.Scheme == "https", "seed_url", "http or https URL expected")
+		check(u.Hostname() != "", "seed_url", "no host in %q", c.SeedURL)
+		seedHost = u.Hostname()
+	}
+	check(c.MaxPages >= 1, "max_pages", "must be at least 1")
+	check(c.MaxParallelRequests >= 1 && c.MaxParallelRequests <= maxParallelRequests,
+		"max_parallel_requests", "must be between 1 and %d", maxParallelRequests)
+	check(c.MaxBodySize >= 0, "max_body_size", "must not be negative")
+	check(!c.TruncateLargeBodies || c.MaxBodySize > 0, "truncate_large_bodies", "requires max_body_size")
+	check(c.MinTransferRate >= 0, "min_transfer_rate", "must not be negative")
+	check(c.NearDuplicateDistance >= -1 && c.NearDuplicateDistance <= 64,
+		"near_duplicate_distance", "must be between -1 and 64")
+	if _, err := buildLinkSources(c.LinkSources); err != nil {
+		check(false, "link_sources", "%s", err)
+	}
+	if _, err := c.Scope.build(seedHost, c.AllowWWWPrefix); err != nil {
+		check(false, "scope", "%s", err)
+	}
+	for i, port := range c.Scope.Ports {
+		check(port > 0 && port <= 65535, fmt.Sprintf("scope.ports[%d]", i), "%d is not a valid port", port)
+	}
+	if c.Output.Format == output_sink.FormatSQLite {
+		check(c.Output.Path != "", "output.path", "required for sqlite format")
+		check(c.Output.MaxSize == 0, "output.max_size", "not supported for sqlite format")
+		check(!c.Output.Gzip, "output.gzip", "not supported for sqlite format")
+	} else {
+		if _, err := output_sink.New(c.Output.Format, io.Discard, false); err != nil {
+			check(false, "output.format", "%s", err)
+		}
+		check(c.Output.MaxSize >= 0, "output.max_size", "must not be negative")
+		check(c.Output.MaxSize == 0 || c.Output.Path != "", "output.max_size", "requires output.path")
+		check(!c.Output.Gzip || c.Output.Path != "", "output.gzip", "requires output.path")
+	}
+	checkAddress := func(key, addr string) {
+		if addr != "" {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				check(false, key, "%s", err)
+			}
+		}
+	}
+	checkAddress("metrics_address", c.MetricsAddress)
+	checkAddress("control_address", c.ControlAddress)
+	if _, _, err := net.SplitHostPort(c.ControlAddress); err == nil {
+		check(c.ControlToken != "" || loopbackAddress(c.ControlAddress),
+			"control_token", "required unless control_address is a loopback address")
+	}
+	checkAddress("distributed.coordinator_address", c.Distributed.CoordinatorAddress)
+	if _, _, err := net.SplitHostPort(c.Distributed.CoordinatorAddress); err == nil {
+		check(c.Distributed.Token != "" || loopbackAddress(loopbackDefault(c.Distributed.CoordinatorAddress)),
+			"distributed.token", "required unless distributed.coordinator_address is a loopback address")
+	}
+	check(c.Distributed.LeaseTimeout >= 1, "distributed.lease_timeout", "must be at least 1")
+	check(c.Distributed.WorkerBatchSize >= 1 && c.Distributed.WorkerBatchSize <= maxParallelRequests,
+		"distributed.worker_batch_size", "must be between 1 and %d", maxParallelRequests)
+	if _, err := c.Normalisation.build(); err != nil {
+		check(false, "normalisation", "%s", err)
+	}
+	if _, err := buildURLRules(c.URLRules); err != nil {
+		check(false, "url_rules", "%s", err)
+	}
+	if _, err := buildHeaderRules(c.Headers); err != nil {
+		check(false, "headers", "%s", err)
+	}
+	check(c.Visited.Store == "" || c.Visited.Store == visitedStoreMemory || c.Visited.Store == visitedStoreBloom ||
+		c.Visited.Store == visitedStoreDisk, "visited.store", "unknown store %q", c.Visited.Store)
+	check(c.Visited.Store != visitedStoreBloom || c.Visited.FalsePositiveRate > 0 && c.Visited.FalsePositiveRate < 1,
+		"visited.false_positive_rate", "must be between 0 and 1 exclusive")
+	check(c.Visited.Path == "" || c.Visited.Store == visitedStoreDisk, "visited.path", "requires store 'disk'")
+	if c.Visited.Store == visitedStoreBloom || c.Visited.Store == visitedStoreDisk {
+		// Page indexes are kept in memory and grow with the crawl, defeating the purpose of these stores
+		check(c.NearDuplicateDistance == -1, "near_duplicate_distance",
+			"must be -1 with %q visited store, near-duplicate detection keeps every page in memory", c.Visited.Store)
+		check(!c.CanonicalReport, "canonical_report",
+			"not supported with %q visited store, the report keeps every page in memory", c.Visited.Store)
+	}
+	check(c.Log.Format == "" || c.Log.Format == logFormatText || c.Log.Format == logFormatJSON,
+		"log.format", "unknown log format %q", c.Log.Format)
+	if c.Log.Level != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+			check(false, "log.level", "unknown log level %q", c.Log.Level)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -1,136 +1,563 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/control"
+	"github.com/dmitry-vovk/wcrawler/crawler/distributed"
+	"github.com/dmitry-vovk/wcrawler/crawler/metrics"
+	"github.com/dmitry-vovk/wcrawler/crawler/output_sink"
 	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/dmitry-vovk/wcrawler/crawler/progress"
+	"github.com/dmitry-vovk/wcrawler/crawler/robots_txt"
+	"github.com/dmitry-vovk/wcrawler/crawler/text_index"
 	"github.com/dmitry-vovk/wcrawler/crawler/url_filter"
-	"github.com/temoto/robotstxt"
-)
-
-const (
-	defaultConfigFile = "config.json"
-	configFileEnv     = "CRAWLER_CONFIG"
 )
 
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	cfg := mustLoadConfig()
+	os.Exit(run(os.Args[1:]))
+}
+
+// How long the finished crawl waits for workers to ask for more jobs and learn it is finished
+const workersWait = 10 * time.Second
+
+// robots.txt is plain text, servers negotiating content get it whatever they prefer
+const robotsAcceptHeader = "text/plain,*/*;q=0.8"
+
+// Workers read the coordinator token from the environment variable setting distributed.token
+const workerTokenEnv = envPrefix + "DISTRIBUTED_TOKEN"
+
+// crawl runs the crawler and prints the results, returns exit code;
+// interrupted crawl finishes requests in flight, outputs are flushed and closed
+func crawl(cfg *Config, args []string) int {
+	if len(args) > 0 {
+		slog.Error("Unexpected arguments", "args", strings.Join(args, " "))
+		return 2
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	return runCrawl(ctx, cfg)
+}
+
+// runCrawl crawls until finished or the context is cancelled, returns exit code
+func runCrawl(ctx context.Context, cfg *Config) int {
 	start := time.Now()
-	if err := mustBuildCrawler(cfg, printResults).Run(cfg.SeedURL); err != nil {
-		log.Printf("Error running crawler: %s\n", err)
-	} else {
-		log.Printf("Crawler finished in %s\n", time.Since(start))
+	// Config contains only JSON-compatible values
+	redacted := cfg.redacted()
+	stored, _ := json.Marshal(redacted)
+	var display *progress.Display
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if cfg.Progress && progress.IsTerminal(os.Stderr) {
+		display = progress.New(os.Stderr, cfg.MaxPages).Width(progress.TerminalWidth(os.Stderr))
+		stderr = display.Writer(os.Stderr)
+		// Results printed on the same terminal must not overwrite the view
+		if progress.IsTerminal(os.Stdout) {
+			stdout = display.Writer(os.Stdout)
+		}
+	}
+	logger := cfg.Log.build(stderr, cfg.Debug)
+	slog.SetDefault(logger)
+	sink, err := cfg.Output.build(cfg.PrintMetadata, output_sink.Run{SeedURL: redacted.SeedURL, Config: string(stored)}, stdout)
+	if err != nil {
+		logger.Error("Error opening output", "error", err)
+		return 1
+	}
+	defer func() {
+		if err := sink.Close(); err != nil {
+			logger.Error("Error closing output", "error", err)
+		}
+	}()
+	resultHandler := sinkHandler(sink)
+	if cfg.IndexPath != "" {
+		index, err := text_index.Create(cfg.IndexPath)
+		if err != nil {
+			logger.Error("Error creating search index", "path", cfg.IndexPath, "error", err)
+			return 1
+		}
+		// Pages indexed until the crawl stops are kept whatever the outcome
+		defer func() {
+			if err := index.Close(); err != nil {
+				logger.Error("Error saving search index", "error", err)
+			} else {
+				logger.Info("Search index saved", "pages", index.Len(), "path", cfg.IndexPath)
+			}
+		}()
+		resultHandler = indexingHandler(index, logger, resultHandler)
+	}
+	var m *metrics.Metrics
+	var onReject func(string, error)
+	if cfg.MetricsAddress != "" {
+		m = metrics.New()
+		resultHandler, onReject = m.ResultHandler(resultHandler), m.ObserveRejection
+	}
+	if display != nil {
+		resultHandler = display.ResultHandler(resultHandler)
+	}
+	c, err := buildCrawler(cfg, logger, resultHandler, onReject)
+	if err != nil {
+		logger.Error("Invalid configuration", "error", err)
+		return 2
+	}
+	visited, err := cfg.Visited.build()
+	if err != nil {
+		logger.Error("Error opening visited links store", "error", err)
+		return 1
+	}
+	if closer, ok := visited.(io.Closer); ok {
+		defer func() {
+			if err := closer.Close(); err != nil {
+				logger.Error("Error closing visited links store", "error", err)
+			}
+		}()
 	}
+	c.VisitedSet(visited)
+	if cfg.SkipCanonicalDuplicates {
+		canonicals, err := cfg.Visited.buildCanonicals()
+		if err != nil {
+			logger.Error("Error opening seen canonical URLs store", "error", err)
+			return 1
+		}
+		if closer, ok := canonicals.(io.Closer); ok {
+			defer func() {
+				if err := closer.Close(); err != nil {
+					logger.Error("Error closing seen canonical URLs store", "error", err)
+				}
+			}()
+		}
+		c.CanonicalSet(canonicals)
+	}
+	if m != nil {
+		m.WatchFrontier(c)
+		stop, err := serve(cfg.MetricsAddress, "/metrics", m.Handler())
+		if err != nil {
+			logger.Error("Error serving metrics", "error", err)
+			return 1
+		}
+		defer stop()
+	}
+	if cfg.ControlAddress != "" {
+		stop, err := serve(cfg.ControlAddress, "/", control.NewHandler(c, cfg.ControlToken))
+		if err != nil {
+			logger.Error("Error serving control API", "error", err)
+			return 1
+		}
+		defer stop()
+	}
+	var coordinator *distributed.Coordinator
+	if cfg.Distributed.CoordinatorAddress != "" {
+		settings, _ := json.Marshal(cfg.workerSettings())
+		coordinator = distributed.
+			NewCoordinator(time.Duration(cfg.Distributed.LeaseTimeout) * time.Second).
+			Settings(settings).
+			Token(cfg.Distributed.Token).
+			Logger(logger)
+		c.JobQueue(coordinator)
+		stop, err := serve(loopbackDefault(cfg.Distributed.CoordinatorAddress), "/", coordinator.Handler())
+		if err != nil {
+			logger.Error("Error serving worker API", "error", err)
+			return 1
+		}
+		defer stop()
+	}
+	if display != nil {
+		display.Start(c)
+	}
+	stopOnCancel := context.AfterFunc(ctx, func() {
+		logger.Warn("Interrupted, finishing requests in flight")
+		c.Stop()
+	})
+	defer stopOnCancel()
+	err = c.Run(cfg.SeedURL)
+	if s, ok := sink.(interface{ MarkInterrupted() }); ok && (err != nil || ctx.Err() != nil) {
+		s.MarkInterrupted()
+	}
+	if coordinator != nil {
+		// Workers exit once they are told the coordinator is closed
+		coordinator.Close()
+		if n := coordinator.WaitWorkers(workersWait); n > 0 {
+			logger.Warn("Workers not told the crawl is finished", "workers", n)
+		}
+	}
+	if display != nil {
+		display.Stop()
+	}
+	if err != nil {
+		logger.Error("Error running crawler", "error", err)
+		return 1
+	}
+	if ctx.Err() != nil {
+		logger.Warn("Crawler interrupted", "duration", time.Since(start))
+		return 1
+	}
+	logger.Info("Crawler finished", "duration", time.Since(start))
+	if cfg.CanonicalReport {
+		printCanonicalReport(c.CanonicalReport())
+	}
+	if cfg.DuplicateReport {
+		printDuplicateReport(c.DuplicateReport())
+	}
+	return 0
 }
 
-func mustBuildCrawler(cfg *Config, resultsCallback func(string, []string)) *crawler.Crawler {
-	// Validate seed URL
+// buildCrawler assembles a crawler instance according to the config,
+// onReject is called for links rejected by the filter if not nil
+func buildCrawler(cfg *Config, logger *slog.Logger, resultHandler func(crawler.Result), onReject func(string, error)) (*crawler.Crawler, error) {
+	u, err := parseSeedURL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	linkSources, err := buildLinkSources(cfg.LinkSources)
+	if err != nil {
+		return nil, fmt.Errorf("link sources: %w", err)
+	}
+	fetcher, err := buildFetcher(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := buildFilter(cfg, u, logger)
+	if err != nil {
+		return nil, err
+	}
+	filter.OnReject(onReject)
+	return crawler.
+		New(fetcher, filter, nil).
+		Logger(logger).
+		ResultHandler(resultHandler).
+		MaxPages(cfg.MaxPages).
+		MaxParallelRequests(cfg.MaxParallelRequests).
+		LinkSources(linkSources...).
+		UserAgent(cfg.UserAgent).
+		IgnoreRobotsMeta(cfg.IgnoreRobotsMeta).
+		SkipCanonicalDuplicates(cfg.SkipCanonicalDuplicates).
+		CollectCanonicals(cfg.CanonicalReport).
+		NearDuplicateDistance(cfg.NearDuplicateDistance).
+		SkipNearDuplicateLinks(cfg.SkipNearDuplicateLinks), nil
+}
+
+// parseSeedURL validates seed URL
+func parseSeedURL(cfg *Config) (*url.URL, error) {
 	u, err := url.Parse(cfg.SeedURL)
 	if err != nil {
-		log.Printf("Error parsing seed URL: %s", err)
-		os.Exit(2)
+		return nil, fmt.Errorf("seed URL: %w", err)
 	} else if u.Hostname() == "" {
-		log.Print("Invalid seed URL")
-		os.Exit(2)
+		return nil, fmt.Errorf("seed URL: no host in %q", cfg.SeedURL)
 	}
-	// Initialize dependencies
-	filter := url_filter.
-		NewFilter(u.Hostname()).
-		AllowWWWPrefix(cfg.AllowWWWPrefix)
-	if !cfg.IgnoreRobotsTxt {
-		u.Path = "/robots.txt"
-		if robots := fetchRobots(u.String()); robots != nil {
-			filter.WithRobots(robots, cfg.UserAgent)
-		}
+	return u, nil
+}
+
+// buildFetcher returns page fetcher configured for the crawl
+func buildFetcher(cfg *Config, logger *slog.Logger) (*page_fetcher.Fetcher, error) {
+	headerRules, err := buildHeaderRules(cfg.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("header rules: %w", err)
 	}
-	fetcher := page_fetcher.NewFetcher(
+	return page_fetcher.NewFetcher(
 		page_fetcher.WithUserAgent(cfg.UserAgent),
 		page_fetcher.WithHeadRequests(cfg.DoHeadRequests),
-	)
-	// Assemble a crawler instance
-	return crawler.
-		New(fetcher, filter, resultsCallback).
-		MaxPages(cfg.MaxPages).
-		MaxParallelRequests(cfg.MaxParallelRequests)
+		page_fetcher.WithHeaderRules(headerRules...),
+		page_fetcher.WithLogger(logger),
+		page_fetcher.WithMaxBodySize(cfg.MaxBodySize, cfg.TruncateLargeBodies),
+		page_fetcher.WithMinTransferRate(cfg.MinTransferRate),
+	), nil
 }
 
-// Config contains all the variables needed for crawler
-type Config struct {
-	// Starting URL
-	SeedURL string `json:"seed_url"`
-	// List of upper level domains to allow:
-	// e.g. with "www" treat example.com and www.example.com as the same domain
-	AllowWWWPrefix bool `json:"allow_www_prefix"`
-	// Whether to take in account robots.txt rules
-	IgnoreRobotsTxt bool `json:"ignore_robots_txt"`
-	// Do HEAD requests before GET requests to avoid fetching inappropriate links
-	DoHeadRequests bool `json:"do_head_requests"`
-	// HTTP user agent string to use
-	UserAgent string `json:"user_agent"`
-	// Do not crawl more than this number of pages
-	MaxPages uint64 `json:"max_pages"`
-	// How many requests to allow to run in parallel
-	MaxParallelRequests uint `json:"max_parallel_requests"`
+// buildRobotsFetcher returns fetcher for robots.txt files: page size, rate and content type limits do not apply,
+// the robots.txt manager reads up to its own size limit
+func buildRobotsFetcher(cfg *Config, logger *slog.Logger) (*page_fetcher.Fetcher, error) {
+	headerRules, err := buildHeaderRules(cfg.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("header rules: %w", err)
+	}
+	return page_fetcher.NewFetcher(
+		page_fetcher.WithUserAgent(cfg.UserAgent),
+		page_fetcher.WithAccept(robotsAcceptHeader),
+		page_fetcher.WithHeaderRules(headerRules...),
+		page_fetcher.WithLogger(logger),
+	), nil
 }
 
-func mustLoadConfig() *Config {
-	configFile := defaultConfigFile
-	if len(os.Args) == 2 {
-		configFile = os.Args[1]
-	} else if cfgPath := os.Getenv(configFileEnv); cfgPath != "" {
-		configFile = cfgPath
+// buildFilter returns URL filter for the seed URL, robots.txt is fetched with its own fetcher
+func buildFilter(cfg *Config, u *url.URL, logger *slog.Logger) (*url_filter.NormalizingFilter, error) {
+	rules, err := buildURLRules(cfg.URLRules)
+	if err != nil {
+		return nil, fmt.Errorf("URL rules: %w", err)
+	}
+	normalisation, err := cfg.Normalisation.build()
+	if err != nil {
+		return nil, fmt.Errorf("normalisation: %w", err)
 	}
-	cfg, err := readConfig(configFile)
+	scope, err := cfg.Scope.build(u.Hostname(), cfg.AllowWWWPrefix)
 	if err != nil {
-		log.Printf("Error reading config file %q: %s", configFile, err)
-		os.Exit(1)
+		return nil, fmt.Errorf("scope: %w", err)
 	}
-	return cfg
+	filter := url_filter.
+		NewFilter(u.Hostname()).
+		WithScope(scope).
+		WithRules(rules...).
+		WithNormalisation(normalisation).
+		WithLogger(logger)
+	if !cfg.IgnoreRobotsTxt {
+		fetcher, err := buildRobotsFetcher(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		filter.WithRobots(robots_txt.NewManager(fetcher, u, robots_txt.WithLogger(logger)), cfg.UserAgent)
+	}
+	return filter, nil
 }
 
-// readConfig returns config read from file or error
-func readConfig(filePath string) (*Config, error) {
-	f, err := os.Open(filePath)
+// serve starts serving the handler at the path in background, returns function stopping the server
+func serve(addr, path string, handler http.Handler) (stop func(), err error) {
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Error serving", "path", path, "error", err)
+		}
+	}()
+	slog.Info("Serving", "url", "http://"+listener.Addr().String()+path)
+	return func() {
+		_ = server.Close()
+	}, nil
+}
+
+// sinkHandler returns results callback writing every page result into the sink
+func sinkHandler(sink output_sink.OutputSink) func(crawler.Result) {
+	return func(result crawler.Result) {
+		if err := sink.Write(result); err != nil {
+			slog.Error("Error writing result", "url", result.Link, "error", err)
+		}
+	}
+}
+
+func printCanonicalReport(report crawler.CanonicalReport) {
+	canonicals := make([]string, 0, len(report.Clusters))
+	for canonical := range report.Clusters {
+		canonicals = append(canonicals, canonical)
+	}
+	sort.Strings(canonicals)
+	for _, canonical := range canonicals {
+		fmt.Printf("Pages with canonical URL %s\n", canonical)
+		for _, link := range report.Clusters[canonical] {
+			fmt.Printf("\t%s\n", link)
+		}
+	}
+	for _, chain := range report.Chains {
+		fmt.Printf("Canonical chain: %s\n", strings.Join(chain, " -> "))
+	}
+	for _, loop := range report.Loops {
+		fmt.Printf("Canonical loop: %s -> %s\n", strings.Join(loop, " -> "), loop[0])
+	}
+	pages := make([]string, 0, len(report.CrossDomain))
+	for page := range report.CrossDomain {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	for _, page := range pages {
+		fmt.Printf("Cross-domain canonical: %s -> %s\n", page, report.CrossDomain[page])
+	}
+}
+
+func printDuplicateReport(clusters [][]string) {
+	for _, cluster := range clusters {
+		fmt.Printf("Near-duplicates of the page %s\n", cluster[0])
+		for _, link := range cluster[1:] {
+			fmt.Printf("\t%s\n", link)
+		}
+	}
+}
+
+// indexingHandler wraps results callback to add crawled pages to the search index
+func indexingHandler(index *text_index.Index, logger *slog.Logger, next func(crawler.Result)) func(crawler.Result) {
+	return func(result crawler.Result) {
+		if result.Error == nil && result.Text != "" {
+			var title string
+			if result.Metadata != nil {
+				title = result.Metadata.Title
+			}
+			if err := index.Add(result.Link, title, result.Text); err != nil {
+				logger.Error("Error indexing page", "url", result.Link, "error", err)
+			}
+		}
+		next(result)
+	}
+}
+
+// search queries search index built by the crawler: crawler search <index file> <query>
+func search(_ *Config, args []string) int {
+	if len(args) < 2 {
+		slog.Error("Usage: crawler search <index file> <query>")
+		return 2
+	}
+	index, err := text_index.Open(args[0])
+	if err != nil {
+		slog.Error("Error opening search index", "path", args[0], "error", err)
+		return 1
+	}
 	defer func() {
-		// We do not care about errors here
-		_ = f.Close()
+		_ = index.Close()
 	}()
-	var cfg Config
-	err = json.NewDecoder(f).Decode(&cfg)
-	return &cfg, err
+	matches, err := index.Search(strings.Join(args[1:], " "))
+	if err != nil {
+		slog.Error("Error searching", "error", err)
+		return 2
+	}
+	for _, m := range matches {
+		if m.Title != "" {
+			fmt.Printf("%s (%s)\n", m.URL, m.Title)
+		} else {
+			fmt.Printf("%s\n", m.URL)
+		}
+		fmt.Printf("\t%s\n", m.Snippet)
+	}
+	slog.Info("Search finished", "pages", len(matches))
+	return 0
 }
 
-// fetchRobots tries to get 'robots.txt' file for the seed URL
-// for simplicity, it is ok if 'robots.txt' could not be fetched
-// see https://developers.google.com/search/reference/robots_txt#handling-http-result-codes
-func fetchRobots(link string) *robotstxt.RobotsData {
-	resp, err := http.Get(link)
+// worker fetches and parses pages for the coordinator: crawler worker <coordinator URL>;
+// crawl settings come from the coordinator, the worker exits once the crawl is finished.
+// The coordinator token is read from the environment, so it does not show in the process list
+func worker(_ *Config, args []string) int {
+	if len(args) != 1 {
+		slog.Error("Usage: crawler worker <coordinator URL>")
+		return 2
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	token := os.Getenv(workerTokenEnv)
+	settings, err := distributed.Settings(ctx, args[0], token)
 	if err != nil {
-		log.Printf("Error fetching robots.txt: %s", err)
-		return nil
+		slog.Error("Error getting crawl settings", "url", args[0], "error", err)
+		return 1
 	}
-	r, err := robotstxt.FromResponse(resp)
+	cfg := defaultConfig()
+	if err := json.Unmarshal(settings, &cfg); err != nil {
+		slog.Error("Invalid crawl settings", "error", err)
+		return 1
+	}
+	if err := cfg.validate(); err != nil {
+		slog.Error("Invalid crawl settings", "error", err)
+		return 1
+	}
+	logger := cfg.Log.build(os.Stderr, cfg.Debug)
+	slog.SetDefault(logger)
+	c, err := buildCrawler(&cfg, logger, nil, nil)
 	if err != nil {
-		log.Printf("Error parsing robots.txt: %s", err)
-		return nil
+		logger.Error("Invalid crawl settings", "error", err)
+		return 1
 	}
-	return r
+	logger.Info("Worker started", "coordinator", args[0], "batch_size", cfg.Distributed.WorkerBatchSize)
+	err = distributed.
+		NewWorker(args[0], c).
+		Token(token).
+		BatchSize(cfg.Distributed.WorkerBatchSize).
+		Logger(logger).
+		Run(ctx)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		logger.Error("Worker failed", "error", err)
+		return 1
+	}
+	logger.Info("Worker finished")
+	return 0
 }
 
-func printResults(link string, links []string) {
-	fmt.Printf("Links found on the page %s\n", link)
-	for i := range links {
-		fmt.Printf("\t%s\n", links[i])
+// explain prints step-by-step decisions the crawler makes about the URL,
+// returns non-zero exit code if the URL would be excluded
+func explain(cfg *Config, args []string) int {
+	if len(args) != 1 {
+		slog.Error("Usage: crawler explain [options] <url>")
+		return 2
+	}
+	seed, err := parseSeedURL(cfg)
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		return 2
+	}
+	fetcher, err := buildFetcher(cfg, slog.Default())
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		return 2
+	}
+	filter, err := buildFilter(cfg, seed, slog.Default())
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		return 2
+	}
+	fmt.Printf("Explaining %s\n", args[0])
+	steps, link, err := filter.Explain(args[0])
+	for i := range steps {
+		fmt.Printf("%2d. %s\n", i+1, steps[i])
+	}
+	if err != nil {
+		fmt.Printf("Excluded: %s\n", err)
+		return 1
+	}
+	n := len(steps) + 1
+	// Normalised link is always parseable
+	u, _ := url.Parse(link)
+	if explainFetch(os.Stdout, fetcher, cfg.DoHeadRequests, u, n) {
+		return 1
+	}
+	fmt.Printf("%2d. page budget: fetched only if reached within max_pages=%d\n", n+1, cfg.MaxPages)
+	fmt.Printf("Included as %s\n", link)
+	return 0
+}
+
+// explainFetch requests the link the way the crawler would, HEAD only if HEAD requests are enabled,
+// and writes the outcome as step n; returns true if the link is excluded.
+// Only content type and size exclude pages, other failures and statuses make the crawler record the page as failed
+func explainFetch(w io.Writer, fetcher *page_fetcher.Fetcher, doHeadRequests bool, u *url.URL, n int) bool {
+	req := crawler.NewRequest(u, "")
+	if doHeadRequests {
+		resp, err := fetcher.Probe(req)
+		switch {
+		case resp == nil:
+			_, _ = fmt.Fprintf(w, "%2d. HEAD: request failed, GET is done anyway: %s\n", n, err)
+		case err != nil:
+			_, _ = fmt.Fprintf(w, "%2d. HEAD: rejected, %s (status %d, Content-Type %q, Content-Length %q)\n",
+				n, err, resp.StatusCode, resp.Headers.Get("Content-Type"), resp.Headers.Get("Content-Length"))
+			_, _ = fmt.Fprintf(w, "Excluded: %s\n", err)
+			return true
+		default:
+			_, _ = fmt.Fprintf(w, "%2d. HEAD: status %d, Content-Type %q\n", n, resp.StatusCode, resp.Headers.Get("Content-Type"))
+		}
+		return false
+	}
+	resp, err := fetcher.Fetch(req)
+	switch {
+	case errors.Is(err, page_fetcher.ErrBadContentType) || errors.Is(err, page_fetcher.ErrBodyTooLarge):
+		_, _ = fmt.Fprintf(w, "%2d. GET: rejected, %s\n", n, err)
+		_, _ = fmt.Fprintf(w, "Excluded: %s\n", err)
+		return true
+	case err != nil:
+		_, _ = fmt.Fprintf(w, "%2d. GET: request failed, the page is recorded as failed: %s\n", n, err)
+		return false
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = fmt.Fprintf(w, "%2d. GET: status %d, the page is recorded as failed\n", n, resp.StatusCode)
+	} else {
+		_, _ = fmt.Fprintf(w, "%2d. GET: status %d, Content-Type %q\n", n, resp.StatusCode, resp.Headers.Get("Content-Type"))
 	}
+	return false
 }
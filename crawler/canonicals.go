@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// canonicalIndex maps crawled page URLs to their canonical URLs
+type canonicalIndex map[string]string
+
+// CanonicalReport summarises canonical URL relations between crawled pages
+type CanonicalReport struct {
+	// Canonical URLs declared by more than one page, mapped to the declaring pages
+	Clusters map[string][]string
+	// Chains of canonical URLs pointing to pages declaring yet another canonical, e.g. A -> B -> C
+	Chains [][]string
+	// Canonical URLs pointing back to where they started, e.g. A -> B -> A
+	Loops [][]string
+	// Pages declaring canonical URL on another host, mapped to their canonical URLs
+	CrossDomain map[string]string
+}
+
+func (ci canonicalIndex) add(link, canonical string) {
+	ci[link] = canonical
+}
+
+// report builds canonical relations report
+func (ci canonicalIndex) report() CanonicalReport {
+	r := CanonicalReport{
+		Clusters:    make(map[string][]string),
+		CrossDomain: make(map[string]string),
+	}
+	pages := make([]string, 0, len(ci))
+	for page := range ci {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	for _, page := range pages {
+		canonical := ci[page]
+		r.Clusters[canonical] = append(r.Clusters[canonical], page)
+		if !sameHost(page, canonical) {
+			r.CrossDomain[page] = canonical
+		}
+	}
+	for canonical, cluster := range r.Clusters {
+		if len(cluster) < 2 {
+			delete(r.Clusters, canonical)
+		}
+	}
+	seenLoops := make(map[string]struct{})
+	for _, page := range pages {
+		chain, loop := ci.follow(page)
+		if loop {
+			key := loopKey(chain)
+			if _, ok := seenLoops[key]; !ok {
+				seenLoops[key] = struct{}{}
+				r.Loops = append(r.Loops, chain)
+			}
+		} else if len(chain) > 2 {
+			r.Chains = append(r.Chains, chain)
+		}
+	}
+	return r
+}
+
+// follow walks canonical URLs starting from the page, returning visited URLs
+// and whether the walk got back to an already visited URL
+func (ci canonicalIndex) follow(page string) ([]string, bool) {
+	chain := []string{page}
+	visited := map[string]struct{}{page: {}}
+	for link := page; ; {
+		canonical, ok := ci[link]
+		if !ok || canonical == link {
+			return chain, false
+		}
+		if _, ok := visited[canonical]; ok {
+			if canonical != page {
+				// Walk ran into a loop not including the page, it is reported starting from its members
+				return chain, false
+			}
+			return chain, true
+		}
+		chain = append(chain, canonical)
+		visited[canonical] = struct{}{}
+		link = canonical
+	}
+}
+
+// loopKey returns loop identifier not depending on the starting point
+func loopKey(loop []string) string {
+	sorted := make([]string, len(loop))
+	copy(sorted, loop)
+	sort.Strings(sorted)
+	return strings.Join(sorted, " ")
+}
+
+// sameHost tells if both URLs belong to the same host
+func sameHost(a, b string) bool {
+	au, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	bu, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(au.Hostname(), bu.Hostname())
+}
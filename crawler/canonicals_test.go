@@ -0,0 +1,127 @@
+package crawler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/dmitry-vovk/wcrawler/crawler/visited_set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalReport(t *testing.T) {
+	ci := canonicalIndex{
+		"http://example.com/":        "http://example.com/",
+		"http://example.com/?a=1":    "http://example.com/",
+		"http://example.com/?a=2":    "http://example.com/",
+		"http://example.com/old":     "http://example.com/older",
+		"http://example.com/older":   "http://example.com/oldest",
+		"http://example.com/oldest":  "http://example.com/oldest",
+		"http://example.com/ping":    "http://example.com/pong",
+		"http://example.com/pong":    "http://example.com/ping",
+		"http://example.com/partner": "http://partner.com/page",
+	}
+	assert.Equal(t, CanonicalReport{
+		Clusters: map[string][]string{
+			"http://example.com/": {
+				"http://example.com/",
+				"http://example.com/?a=1",
+				"http://example.com/?a=2",
+			},
+			"http://example.com/oldest": {
+				"http://example.com/older",
+				"http://example.com/oldest",
+			},
+		},
+		Chains: [][]string{
+			{"http://example.com/old", "http://example.com/older", "http://example.com/oldest"},
+		},
+		Loops: [][]string{
+			{"http://example.com/ping", "http://example.com/pong"},
+		},
+		CrossDomain: map[string]string{
+			"http://example.com/partner": "http://partner.com/page",
+		},
+	}, ci.report())
+}
+
+func TestCrawler_SkipCanonicalDuplicates(t *testing.T) {
+	fetcher := pagesFetcher{
+		"http://example.com/": `<a href="/shoes?color=red">Red</a><a href="/shoes?color=blue">Blue</a>`,
+		"http://example.com/shoes?color=red": `<link rel="canonical" href="/shoes">
+			<a href="/red">Red</a>`,
+		"http://example.com/shoes?color=blue": `<link rel="canonical" href="/shoes">
+			<a href="/blue">Blue</a>`,
+		"http://example.com/red":   `<a href="/shoes">All shoes</a>`,
+		"http://example.com/blue":  `Blue`,
+		"http://example.com/shoes": `<link rel="canonical" href="/shoes">Shoes`,
+	}
+	for _, skip := range []bool{false, true} {
+		var results []Result
+		c := New(fetcher, tFilter, nil).
+			MaxPages(10).
+			SkipCanonicalDuplicates(skip).
+			CollectCanonicals(true).
+			ResultHandler(func(result Result) {
+				results = append(results, result)
+			})
+		if err := c.Run("http://example.com/"); assert.NoError(t, err) {
+			duplicates, canonicalCrawled := 0, false
+			for _, result := range results {
+				if result.CanonicalDuplicate {
+					duplicates++
+				}
+				canonicalCrawled = canonicalCrawled || result.Link == "http://example.com/shoes"
+			}
+			// The canonical page is crawled although pages declaring it have been seen before
+			assert.True(t, canonicalCrawled)
+			if skip {
+				// Either red or blue page is the duplicate, its canonical URL is followed instead of its link
+				assert.Len(t, results, 5)
+				assert.Equal(t, 1, duplicates)
+			} else {
+				assert.Len(t, results, 6)
+				assert.Equal(t, 0, duplicates)
+			}
+			assert.Len(t, c.CanonicalReport().Clusters["http://example.com/shoes"], 3)
+		}
+	}
+}
+
+func TestCrawler_CanonicalSet(t *testing.T) {
+	fetcher := pagesFetcher{
+		"http://example.com/":                `<a href="/shoes?color=red">Red</a>`,
+		"http://example.com/shoes?color=red": `<link rel="canonical" href="/shoes">`,
+	}
+	seen := visited_set.NewMap()
+	c := New(fetcher, tFilter, nil).
+		MaxPages(10).
+		SkipCanonicalDuplicates(true).
+		CanonicalSet(seen).
+		ResultHandler(func(Result) {})
+	if assert.NoError(t, c.Run("http://example.com/")) {
+		for _, link := range []string{"http://example.com/", "http://example.com/shoes"} {
+			added, _ := seen.Add(link)
+			assert.False(t, added, link)
+		}
+		assert.Nil(t, c.canonicals, "canonicals are not collected by default")
+	}
+}
+
+// pagesFetcher serves pages by URL, responding 404 to unknown ones
+type pagesFetcher map[string]string
+
+func (p pagesFetcher) Fetch(r *page_fetcher.Request) (*page_fetcher.Response, error) {
+	html, ok := p[r.URL.String()]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &page_fetcher.Response{
+		URL:        r.URL,
+		StatusCode: status,
+		Body:       io.NopCloser(bytes.NewBufferString(html)),
+	}, nil
+}
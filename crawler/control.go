@@ -0,0 +1,166 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNotRunning       = errors.New("crawler is not running")
+	ErrBadParallelLimit = errors.New("max parallel requests must be at least 1")
+)
+
+// Stats is a snapshot of the crawl progress
+type Stats struct {
+	// Whether Run is in progress
+	Running bool `json:"running"`
+	// Pages processed, including failed ones
+	Visited uint64 `json:"visited"`
+	// Links waiting to be fetched
+	Queued int `json:"queued"`
+	// Pages being fetched and processed
+	InFlight int `json:"in_flight"`
+	// Pages failed to be crawled
+	Errors uint64 `json:"errors"`
+	// Current limit of parallel requests
+	MaxParallelRequests int `json:"max_parallel_requests"`
+	// No new requests are started while paused
+	Paused bool `json:"paused"`
+	// Stop has been requested, Run returns once requests in flight are done
+	Stopping bool `json:"stopping"`
+	// Patterns of links excluded while running
+	Excluded []string `json:"excluded"`
+}
+
+// Request is a page being fetched and processed
+type Request struct {
+	Link    string    `json:"link"`
+	Started time.Time `json:"started"`
+}
+
+// Pause stops starting new requests until Resume is called, requests in flight are completed
+func (c *Crawler) Pause() {
+	c.limiter.pause(true)
+	if c.jobQueue != nil {
+		c.jobQueue.Pause(true)
+	}
+}
+
+// Resume lets new requests start after Pause
+func (c *Crawler) Resume() {
+	c.limiter.pause(false)
+	if c.jobQueue != nil {
+		c.jobQueue.Pause(false)
+	}
+}
+
+// Stop requests graceful stop: no new requests are started, Run returns once requests in flight are done
+func (c *Crawler) Stop() {
+	c.limiter.stop()
+	if c.jobQueue != nil {
+		// Queued jobs are dropped as the queue hands them out
+		c.jobQueue.Pause(false)
+	}
+}
+
+// SetMaxParallelRequests changes the number of parallel requests, may be called while running;
+// the job queue decides how many jobs are processed at once if set
+func (c *Crawler) SetMaxParallelRequests(n uint) error {
+	if n == 0 {
+		return ErrBadParallelLimit
+	}
+	c.limiter.setLimit(int(n))
+	return nil
+}
+
+// AddSeeds queues more links to crawl while running, returns normalised links accepted by the filter;
+// links visited before are accepted but not crawled again
+func (c *Crawler) AddSeeds(links ...string) ([]string, error) {
+	c.mu.Lock()
+	running, queuedLinksC, doneC := c.running, c.queuedLinksC, c.doneC
+	c.mu.Unlock()
+	if !running {
+		return nil, ErrNotRunning
+	}
+	accepted := make([]string, 0, len(links))
+	for _, link := range links {
+		seed, ok := c.filter.Filter(link)
+		if !ok || c.excluded(seed) {
+			continue
+		}
+		select {
+		case queuedLinksC <- Job{Link: seed}:
+			accepted = append(accepted, seed)
+		case <-doneC:
+			return accepted, ErrNotRunning
+		}
+	}
+	return accepted, nil
+}
+
+// Exclude stops crawling links matching the regular expression, including already queued ones
+func (c *Crawler) Exclude(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("exclude pattern: %w", err)
+	}
+	c.mu.Lock()
+	c.exclusions = append(c.exclusions, re)
+	c.mu.Unlock()
+	return nil
+}
+
+// excluded tells if the link matches any of the patterns added with Exclude
+func (c *Crawler) excluded(link string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, re := range c.exclusions {
+		if re.MatchString(link) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns crawl progress, safe to call while running
+func (c *Crawler) Stats() Stats {
+	limit, paused, stopped := c.limiter.state()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	excluded := make([]string, 0, len(c.exclusions))
+	for _, re := range c.exclusions {
+		excluded = append(excluded, re.String())
+	}
+	return Stats{
+		Running:             c.running,
+		Visited:             atomic.LoadUint64(&c.pagesN),
+		Queued:              c.QueueLength(),
+		InFlight:            c.InFlight(),
+		Errors:              atomic.LoadUint64(&c.errorsN),
+		MaxParallelRequests: limit,
+		Paused:              paused,
+		Stopping:            stopped,
+		Excluded:            excluded,
+	}
+}
+
+// Requests returns pages being fetched and processed, oldest first
+func (c *Crawler) Requests() []Request {
+	c.mu.Lock()
+	requests := make([]Request, 0, len(c.requests))
+	for link, started := range c.requests {
+		requests = append(requests, Request{Link: link, Started: started})
+	}
+	c.mu.Unlock()
+	sort.Slice(requests, func(i, j int) bool {
+		if !requests[i].Started.Equal(requests[j].Started) {
+			return requests[i].Started.Before(requests[j].Started)
+		}
+		return requests[i].Link < requests[j].Link
+	})
+	return requests
+}
@@ -0,0 +1,101 @@
+package control
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/json_api"
+)
+
+// Controller is a crawl controllable while running, e.g. *crawler.Crawler
+type Controller interface {
+	Pause()
+	Resume()
+	Stop()
+	SetMaxParallelRequests(n uint) error
+	AddSeeds(links ...string) ([]string, error)
+	Exclude(pattern string) error
+	Stats() crawler.Stats
+}
+
+// NewHandler returns HTTP API controlling the crawl, requests and responses are JSON:
+//
+//	GET  /stats        crawl progress
+//	POST /pause        stop starting new requests
+//	POST /resume       resume starting new requests
+//	POST /stop         finish requests in flight and stop
+//	POST /concurrency  {"max_parallel_requests": 4}
+//	POST /seeds        {"urls": ["https://example.com/new"]}, responds with accepted normalised URLs
+//	POST /exclude      {"pattern": "/archive/"}, a regular expression
+//
+// Requests other than /seeds respond with the stats after the change, errors are reported as {"error": "..."}.
+// If token is set, requests must have "Authorization: Bearer <token>" header; POST requests must have
+// "Content-Type: application/json" header, so browsers cannot forge them with cross-site form submissions
+func NewHandler(c Controller, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		json_api.Respond(w, http.StatusOK, c.Stats())
+	})
+	mux.HandleFunc("POST /pause", func(w http.ResponseWriter, r *http.Request) {
+		c.Pause()
+		json_api.Respond(w, http.StatusOK, c.Stats())
+	})
+	mux.HandleFunc("POST /resume", func(w http.ResponseWriter, r *http.Request) {
+		c.Resume()
+		json_api.Respond(w, http.StatusOK, c.Stats())
+	})
+	mux.HandleFunc("POST /stop", func(w http.ResponseWriter, r *http.Request) {
+		c.Stop()
+		json_api.Respond(w, http.StatusOK, c.Stats())
+	})
+	mux.HandleFunc("POST /concurrency", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			MaxParallelRequests uint `json:"max_parallel_requests"`
+		}
+		if json_api.Decode(w, r, &req) {
+			if err := c.SetMaxParallelRequests(req.MaxParallelRequests); err != nil {
+				respondError(w, err)
+				return
+			}
+			json_api.Respond(w, http.StatusOK, c.Stats())
+		}
+	})
+	mux.HandleFunc("POST /seeds", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			URLs []string `json:"urls"`
+		}
+		if json_api.Decode(w, r, &req) {
+			accepted, err := c.AddSeeds(req.URLs...)
+			if err != nil {
+				respondError(w, err)
+				return
+			}
+			json_api.Respond(w, http.StatusOK, struct {
+				Accepted []string `json:"accepted"`
+			}{accepted})
+		}
+	})
+	mux.HandleFunc("POST /exclude", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Pattern string `json:"pattern"`
+		}
+		if json_api.Decode(w, r, &req) {
+			if err := c.Exclude(req.Pattern); err != nil {
+				respondError(w, err)
+				return
+			}
+			json_api.Respond(w, http.StatusOK, c.Stats())
+		}
+	})
+	return json_api.Authorize(token, mux)
+}
+
+// respondError responds with conflict if the crawl is not running, bad request otherwise
+func respondError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, crawler.ErrNotRunning) {
+		status = http.StatusConflict
+	}
+	json_api.Respond(w, status, json_api.ErrorResponse{Error: err.Error()})
+}
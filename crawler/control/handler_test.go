@@ -0,0 +1,148 @@
+package control
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+)
+
+type testController struct {
+	calls   []string
+	running bool
+	stats   crawler.Stats
+}
+
+func (c *testController) Pause() {
+	c.calls = append(c.calls, "pause")
+	c.stats.Paused = true
+}
+
+func (c *testController) Resume() {
+	c.calls = append(c.calls, "resume")
+	c.stats.Paused = false
+}
+
+func (c *testController) Stop() {
+	c.calls = append(c.calls, "stop")
+	c.stats.Stopping = true
+}
+
+func (c *testController) SetMaxParallelRequests(n uint) error {
+	if n == 0 {
+		return crawler.ErrBadParallelLimit
+	}
+	c.stats.MaxParallelRequests = int(n)
+	return nil
+}
+
+func (c *testController) AddSeeds(links ...string) ([]string, error) {
+	if !c.running {
+		return nil, crawler.ErrNotRunning
+	}
+	return links[:1], nil
+}
+
+func (c *testController) Exclude(pattern string) error {
+	if pattern == "(" {
+		return errors.New("bad pattern")
+	}
+	c.stats.Excluded = append(c.stats.Excluded, pattern)
+	return nil
+}
+
+func (c *testController) Stats() crawler.Stats {
+	return c.stats
+}
+
+func TestHandler(t *testing.T) {
+	c := &testController{stats: crawler.Stats{Visited: 5, Excluded: []string{}}}
+	h := NewHandler(c, "")
+	testCases := []struct {
+		method, path, body string
+		status             int
+		response           string
+	}{
+		{method: "GET", path: "/stats", status: 200,
+			response: `{"running":false,"visited":5,"queued":0,"in_flight":0,"errors":0,"max_parallel_requests":0,"paused":false,"stopping":false,"excluded":[]}`},
+		{method: "POST", path: "/pause", status: 200,
+			response: `{"running":false,"visited":5,"queued":0,"in_flight":0,"errors":0,"max_parallel_requests":0,"paused":true,"stopping":false,"excluded":[]}`},
+		{method: "POST", path: "/resume", status: 200,
+			response: `{"running":false,"visited":5,"queued":0,"in_flight":0,"errors":0,"max_parallel_requests":0,"paused":false,"stopping":false,"excluded":[]}`},
+		{method: "POST", path: "/concurrency", body: `{"max_parallel_requests": 4}`, status: 200,
+			response: `{"running":false,"visited":5,"queued":0,"in_flight":0,"errors":0,"max_parallel_requests":4,"paused":false,"stopping":false,"excluded":[]}`},
+		{method: "POST", path: "/concurrency", body: `{"max_parallel_requests": 0}`, status: 400,
+			response: `{"error":"max parallel requests must be at least 1"}`},
+		{method: "POST", path: "/concurrency", body: `{"parallel": 2}`, status: 400,
+			response: `{"error":"json: unknown field \"parallel\""}`},
+		{method: "POST", path: "/seeds", body: `{"urls": ["http://example.com/a", "mailto:x"]}`, status: 409,
+			response: `{"error":"crawler is not running"}`},
+		{method: "POST", path: "/exclude", body: `{"pattern": "/archive/"}`, status: 200,
+			response: `{"running":false,"visited":5,"queued":0,"in_flight":0,"errors":0,"max_parallel_requests":4,"paused":false,"stopping":false,"excluded":["/archive/"]}`},
+		{method: "POST", path: "/exclude", body: `{"pattern": "("}`, status: 400,
+			response: `{"error":"bad pattern"}`},
+		{method: "POST", path: "/stop", status: 200,
+			response: `{"running":false,"visited":5,"queued":0,"in_flight":0,"errors":0,"max_parallel_requests":4,"paused":false,"stopping":true,"excluded":["/archive/"]}`},
+		{method: "GET", path: "/stop", status: 405},
+		{method: "GET", path: "/unknown", status: 404},
+	}
+	for _, tt := range testCases {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, jsonRequest(tt.method, tt.path, tt.body))
+		assert.Equal(t, tt.status, rec.Code, tt.path)
+		if tt.response != "" {
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"), tt.path)
+			assert.JSONEq(t, tt.response, rec.Body.String(), tt.path)
+		}
+	}
+	assert.Equal(t, []string{"pause", "resume", "stop"}, c.calls)
+
+	c.running = true
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, jsonRequest(http.MethodPost, "/seeds", `{"urls": ["http://example.com/a", "mailto:x"]}`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"accepted":["http://example.com/a"]}`, rec.Body.String())
+}
+
+func TestHandler_Authorize(t *testing.T) {
+	c := &testController{}
+	h := NewHandler(c, "secret")
+	testCases := []struct {
+		name          string
+		authorization string
+		contentType   string
+		status        int
+	}{
+		{name: "no token", contentType: "application/json", status: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer wrong", contentType: "application/json", status: http.StatusUnauthorized},
+		{name: "form post", authorization: "Bearer secret", contentType: "text/plain", status: http.StatusUnsupportedMediaType},
+		{name: "no content type", authorization: "Bearer secret", status: http.StatusUnsupportedMediaType},
+		{name: "authorized", authorization: "Bearer secret", contentType: "application/json; charset=utf-8", status: http.StatusOK},
+	}
+	for _, tt := range testCases {
+		req := httptest.NewRequest(http.MethodPost, "/stop", nil)
+		req.Header.Set("Authorization", tt.authorization)
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, tt.status, rec.Code, tt.name)
+	}
+	assert.Equal(t, []string{"stop"}, c.calls, "only the authorized request is served")
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "GET requests need no content type")
+}
+
+// jsonRequest builds request with JSON content type
+func jsonRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
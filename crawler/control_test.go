@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+)
+
+// siteFetcher serves pages by URL, requests wait for release if it is set
+type siteFetcher struct {
+	pages   map[string]string
+	release chan struct{}
+	mu      sync.Mutex
+	fetched []string
+}
+
+func (f *siteFetcher) Fetch(r *page_fetcher.Request) (*page_fetcher.Response, error) {
+	f.mu.Lock()
+	f.fetched = append(f.fetched, r.URL.String())
+	f.mu.Unlock()
+	if f.release != nil {
+		<-f.release
+	}
+	return &page_fetcher.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewBufferString(f.pages[r.URL.String()])),
+	}, nil
+}
+
+func (f *siteFetcher) urls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.fetched...)
+}
+
+func runAsync(c *Crawler, seed string) chan error {
+	errC := make(chan error, 1)
+	go func() {
+		errC <- c.Run(seed)
+	}()
+	return errC
+}
+
+func TestCrawler_PauseSeedsExclude(t *testing.T) {
+	fetcher := &siteFetcher{pages: map[string]string{
+		"http://example.com/": `<a href="/a">A</a><a href="/b">B</a>`,
+	}}
+	c := New(fetcher, tFilter, nil).MaxPages(10)
+	_, err := c.AddSeeds("http://example.com/c")
+	assert.Equal(t, ErrNotRunning, err)
+	assert.Error(t, c.Exclude("("))
+	c.Pause()
+	errC := runAsync(c, "http://example.com/")
+	assert.Eventually(t, func() bool {
+		stats := c.Stats()
+		return stats.Running && stats.Queued == 1
+	}, time.Second, time.Millisecond)
+	added, err := c.AddSeeds("http://example.com/c", "", "http://example.com/b2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"http://example.com/c", "http://example.com/b2"}, added)
+	}
+	assert.Eventually(t, func() bool {
+		return c.Stats().Queued == 3
+	}, time.Second, time.Millisecond)
+	// Queued link is dropped once it gets its turn
+	assert.NoError(t, c.Exclude(`/b\d*$`))
+	stats := c.Stats()
+	assert.True(t, stats.Paused)
+	assert.Equal(t, []string{`/b\d*$`}, stats.Excluded)
+	assert.Empty(t, fetcher.urls())
+	c.Resume()
+	if assert.NoError(t, <-errC) {
+		assert.ElementsMatch(t, []string{"http://example.com/", "http://example.com/a", "http://example.com/c"}, fetcher.urls())
+	}
+	stats = c.Stats()
+	assert.False(t, stats.Running)
+	assert.Equal(t, uint64(3), stats.Visited)
+	assert.Zero(t, stats.Queued)
+	assert.Zero(t, stats.InFlight)
+}
+
+func TestCrawler_Stop(t *testing.T) {
+	fetcher := &siteFetcher{
+		pages: map[string]string{
+			"http://example.com/": `<a href="/a">A</a><a href="/b">B</a><a href="/c">C</a>`,
+		},
+		release: make(chan struct{}),
+	}
+	c := New(fetcher, tFilter, nil).MaxPages(10)
+	assert.Equal(t, ErrBadParallelLimit, c.SetMaxParallelRequests(0))
+	assert.NoError(t, c.SetMaxParallelRequests(2))
+	errC := runAsync(c, "http://example.com/")
+	fetcher.release <- struct{}{}
+	// Two of the found links are being fetched, the third one waits
+	assert.Eventually(t, func() bool {
+		stats := c.Stats()
+		return stats.InFlight == 2 && stats.Queued == 1
+	}, time.Second, time.Millisecond)
+	requests := c.Requests()
+	if assert.Len(t, requests, 2) {
+		assert.NotEqual(t, requests[0].Link, requests[1].Link)
+		assert.False(t, requests[1].Started.Before(requests[0].Started), "oldest first")
+	}
+	c.Stop()
+	assert.True(t, c.Stats().Stopping)
+	fetcher.release <- struct{}{}
+	fetcher.release <- struct{}{}
+	if assert.NoError(t, <-errC) {
+		assert.Len(t, fetcher.urls(), 3)
+		assert.Equal(t, uint64(3), c.Stats().Visited)
+		assert.Empty(t, c.Requests())
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(1)
+	assert.True(t, l.acquire())
+	acquired := make(chan bool)
+	go func() {
+		acquired <- l.acquire()
+	}()
+	select {
+	case <-acquired:
+		assert.Fail(t, "limit exceeded")
+	case <-time.After(10 * time.Millisecond):
+	}
+	l.setLimit(2)
+	assert.True(t, <-acquired)
+	l.pause(true)
+	l.release()
+	go func() {
+		acquired <- l.acquire()
+	}()
+	select {
+	case <-acquired:
+		assert.Fail(t, "acquired while paused")
+	case <-time.After(10 * time.Millisecond):
+	}
+	l.stop()
+	assert.False(t, <-acquired)
+	assert.False(t, l.acquire())
+	limit, paused, stopped := l.state()
+	assert.Equal(t, 2, limit)
+	assert.True(t, paused)
+	assert.True(t, stopped)
+}
@@ -2,36 +2,69 @@ package crawler
 
 import (
 	"errors"
-	"log"
+	"log/slog"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/dmitry-vovk/wcrawler/crawler/page_parser"
 	"github.com/dmitry-vovk/wcrawler/crawler/types"
+	"github.com/dmitry-vovk/wcrawler/crawler/visited_set"
 )
 
 // Crawler is a page crawler
 type Crawler struct {
-	maxPages            uint64
-	maxParallelRequests uint
-	fetcher             types.Fetcher
-	filter              types.Filter
-	resultCallback      func(string, []string) // Callback function to send page crawl results
-	limiterC            chan struct{}          // Simple limiter
-	queuedLinksC        chan crawlJob          // URLs to be processed
-	processedLinksC     chan crawlResult       // URLs that done processing
-	processingLinks     map[string]struct{}    // Links that are currently being processed
-	processedLinks      map[string]struct{}    // Visited links
-	doneC               chan struct{}          // Done signal
-	pagesN              uint64
-	finished            bool
-}
-
-const defaultMaxParallelRequests = 1
+	maxPages                uint64
+	maxParallelRequests     uint
+	linkSources             []page_parser.LinkSource // Elements to collect links from, a[href] if empty
+	userAgent               string                   // User agent to match agent-specific robots meta tags
+	ignoreRobotsMeta        bool                     // Follow links regardless of nofollow directives
+	skipCanonicalDuplicates bool                     // Do not follow links from pages whose canonical URL has been seen
+	collectCanonicals       bool                     // Keep canonical URLs of all pages for CanonicalReport
+	nearDuplicateDistance   int                      // Maximum SimHash distance of near-duplicates, negative to disable
+	skipNearDuplicateLinks  bool                     // Do not follow links from near-duplicate pages
+	fetcher                 types.Fetcher
+	filter                  types.Filter
+	pageProcessor           PageProcessor          // Fetches and parses pages instead of the crawler if set
+	jobQueue                JobQueue               // Keeps jobs processed elsewhere, request slots are not used if set
+	visitedSet              types.VisitedSet       // Remembers visited links, a new map for every run if not set
+	canonicalSet            types.VisitedSet       // Remembers seen canonical URLs, a new map for every run if not set
+	resultCallback          func(string, []string) // Callback function to send page crawl results
+	resultHandler           func(Result)           // Callback function to send detailed page crawl results
+	limiter                 *limiter               // Parallel requests limiter, also pauses and stops the crawl
+	logger                  *slog.Logger
+	queuedLinksC            chan Job            // URLs to be processed
+	processedLinksC         chan crawlResult    // URLs that done processing
+	processingLinks         map[string]struct{} // Links that are currently being processed
+	visited                 types.VisitedSet    // Visited links
+	canonicals              canonicalIndex      // Page to canonical URL mapping, nil unless collected
+	seenCanonicals          types.VisitedSet    // Canonical URLs declared or crawled, kept apart from visited links
+	duplicates              *duplicateIndex     // Near-duplicate content detection
+	doneC                   chan struct{}       // Done signal
+	pagesN                  uint64
+	queuedN                 int64 // Jobs waiting for a free request slot
+	inFlightN               int64 // Jobs being processed
+	errorsN                 uint64
+	finished                bool
+	mu                      sync.Mutex           // Guards fields below
+	running                 bool                 // Whether Run is in progress
+	exclusions              []*regexp.Regexp     // Patterns of links excluded while running
+	requests                map[string]time.Time // Start times of links being processed
+}
+
+// Number of parallel requests used when not set
+const DefaultMaxParallelRequests = 1
 
 // New creates an instance of Crawler
 func New(fetcher types.Fetcher, filter types.Filter, pageCrawlResultCallback func(string, []string)) *Crawler {
 	return &Crawler{
-		fetcher:        fetcher,
-		filter:         filter,
-		resultCallback: pageCrawlResultCallback,
+		fetcher:               fetcher,
+		filter:                filter,
+		resultCallback:        pageCrawlResultCallback,
+		nearDuplicateDistance: DefaultNearDuplicateDistance,
+		limiter:               newLimiter(DefaultMaxParallelRequests),
+		logger:                slog.Default(),
 	}
 }
 
@@ -44,12 +77,118 @@ func (c *Crawler) MaxPages(maxPages uint64) *Crawler {
 // MaxParallelRequests limit the number or parallel requests
 func (c *Crawler) MaxParallelRequests(maxParallelRequests uint) *Crawler {
 	c.maxParallelRequests = maxParallelRequests
+	if maxParallelRequests > 0 {
+		c.limiter.setLimit(int(maxParallelRequests))
+	}
+	return c
+}
+
+// LinkSources sets the elements links are collected from and followed, only a[href] by default
+func (c *Crawler) LinkSources(sources ...page_parser.LinkSource) *Crawler {
+	c.linkSources = sources
+	return c
+}
+
+// UserAgent sets user agent name to match agent-specific robots meta tags and headers
+func (c *Crawler) UserAgent(userAgent string) *Crawler {
+	c.userAgent = userAgent
+	return c
+}
+
+// IgnoreRobotsMeta sets whether to follow links regardless of nofollow
+// robots meta tags, X-Robots-Tag headers and rel="nofollow" attributes
+func (c *Crawler) IgnoreRobotsMeta(ignore bool) *Crawler {
+	c.ignoreRobotsMeta = ignore
+	return c
+}
+
+// SkipCanonicalDuplicates sets whether to stop following links from pages
+// whose canonical URL has been already seen, the canonical URL is queued instead
+func (c *Crawler) SkipCanonicalDuplicates(skip bool) *Crawler {
+	c.skipCanonicalDuplicates = skip
+	return c
+}
+
+// CollectCanonicals sets whether to keep canonical URLs of all crawled pages for CanonicalReport
+func (c *Crawler) CollectCanonicals(collect bool) *Crawler {
+	c.collectCanonicals = collect
+	return c
+}
+
+// NearDuplicateDistance sets the maximum Hamming distance between SimHashes of near-duplicate pages,
+// negative value disables detection
+func (c *Crawler) NearDuplicateDistance(maxDistance int) *Crawler {
+	c.nearDuplicateDistance = maxDistance
+	return c
+}
+
+// SkipNearDuplicateLinks sets whether to stop following links from near-duplicate pages
+func (c *Crawler) SkipNearDuplicateLinks(skip bool) *Crawler {
+	c.skipNearDuplicateLinks = skip
+	return c
+}
+
+// ResultHandler sets callback function receiving detailed page crawl results
+func (c *Crawler) ResultHandler(handler func(Result)) *Crawler {
+	c.resultHandler = handler
+	return c
+}
+
+// VisitedSet sets store of visited links, e.g. to keep memory flat on very large crawls
+func (c *Crawler) VisitedSet(set types.VisitedSet) *Crawler {
+	c.visitedSet = set
+	return c
+}
+
+// CanonicalSet sets store of canonical URLs seen when skipping canonical duplicates
+func (c *Crawler) CanonicalSet(set types.VisitedSet) *Crawler {
+	c.canonicalSet = set
 	return c
 }
 
+// Logger sets logger for crawl progress and page errors
+func (c *Crawler) Logger(logger *slog.Logger) *Crawler {
+	c.logger = logger
+	return c
+}
+
+// taskSettings returns page processing settings according to crawler settings
+func (c *Crawler) taskSettings() taskSettings {
+	settings := taskSettings{
+		parserOptions:    []page_parser.Option{page_parser.WithUserAgent(c.userAgent)},
+		ignoreRobotsMeta: c.ignoreRobotsMeta,
+	}
+	if len(c.linkSources) > 0 {
+		settings.parserOptions = append(settings.parserOptions, page_parser.WithLinkSources(c.linkSources...))
+	}
+	return settings
+}
+
+// CanonicalReport returns canonical URL relations found during the crawl, to be called after Run returns;
+// empty unless CollectCanonicals is set
+func (c *Crawler) CanonicalReport() CanonicalReport {
+	return c.canonicals.report()
+}
+
+// DuplicateReport returns clusters of near-duplicate pages found during the crawl,
+// each starting with the first crawled page, to be called after Run returns
+func (c *Crawler) DuplicateReport() [][]string {
+	return c.duplicates.report()
+}
+
+// QueueLength returns the number of links waiting to be fetched, safe to call while running
+func (c *Crawler) QueueLength() int {
+	return int(atomic.LoadInt64(&c.queuedN))
+}
+
+// InFlight returns the number of pages being fetched and processed, safe to call while running
+func (c *Crawler) InFlight() int {
+	return int(atomic.LoadInt64(&c.inFlightN))
+}
+
 // Run starts the crawling and blocks until finished
 func (c *Crawler) Run(seedURL string) error {
-	if c.fetcher == nil {
+	if c.fetcher == nil && c.pageProcessor == nil && c.jobQueue == nil {
 		return errors.New("fetcher not set")
 	}
 	if c.filter == nil {
@@ -59,22 +198,33 @@ func (c *Crawler) Run(seedURL string) error {
 	if !ok {
 		return errors.New("bad seed URL")
 	}
-	if c.resultCallback == nil {
-		log.Print("Results callback function not set")
-	}
-	if c.maxParallelRequests == 0 {
-		c.limiterC = make(chan struct{}, defaultMaxParallelRequests)
-	} else {
-		c.limiterC = make(chan struct{}, c.maxParallelRequests)
+	if c.resultCallback == nil && c.resultHandler == nil {
+		c.logger.Warn("Results callback function not set")
 	}
-	c.queuedLinksC = make(chan crawlJob)
+	c.mu.Lock()
+	c.queuedLinksC = make(chan Job)
 	c.processedLinksC = make(chan crawlResult)
 	c.processingLinks = make(map[string]struct{})
-	c.processedLinks = make(map[string]struct{})
+	c.visited = c.visitedSet
+	if c.visited == nil {
+		c.visited = visited_set.NewMap()
+	}
+	c.canonicals = nil
+	if c.collectCanonicals {
+		c.canonicals = make(canonicalIndex)
+	}
+	c.seenCanonicals = c.canonicalSet
+	if c.seenCanonicals == nil {
+		c.seenCanonicals = visited_set.NewMap()
+	}
+	c.duplicates = newDuplicateIndex(c.nearDuplicateDistance)
 	c.doneC = make(chan struct{})
-	log.Printf("Starting from %s", seed)
+	c.requests = make(map[string]time.Time)
+	c.running = true
+	c.mu.Unlock()
+	c.logger.Info("Starting crawl", "url", seed)
 	go c.processor()
-	c.queuedLinksC <- crawlJob{Link: seed}
+	c.queuedLinksC <- Job{Link: seed}
 	<-c.doneC
 	return nil
 }
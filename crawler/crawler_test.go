@@ -2,12 +2,21 @@ package crawler
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
+	"github.com/dmitry-vovk/wcrawler/crawler/fingerprint"
 	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_parser"
 	"github.com/dmitry-vovk/wcrawler/crawler/types"
+	"github.com/dmitry-vovk/wcrawler/crawler/visited_set"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -44,6 +53,59 @@ func TestCrawlerBuild(t *testing.T) {
 	}
 }
 
+func TestCrawler_LinkSources(t *testing.T) {
+	var results []string
+	c := New(tFetcher, tFilter, func(link string, links []string) {
+		results = append(results, links...)
+	}).LinkSources(page_parser.SourceArea)
+	assert.Equal(t, []page_parser.LinkSource{page_parser.SourceArea}, c.linkSources)
+	tFetcher.(*testFetcher).statusCode = 200
+	if err := c.Run("http://example.com/"); assert.NoError(t, err) {
+		assert.Empty(t, results)
+	}
+}
+
+func TestCrawler_ResultHandler(t *testing.T) {
+	var results []Result
+	html := `<html><head><title>Home page</title><meta name="robots" content="noindex"></head><body><a href="/">Home</a></body></html>`
+	c := New(&testFetcher{
+		statusCode: 200,
+		html:       html,
+	}, tFilter, nil).ResultHandler(func(result Result) {
+		results = append(results, result)
+	})
+	if err := c.Run("http://example.com/"); assert.NoError(t, err) && assert.Len(t, results, 1) {
+		if assert.NotNil(t, results[0].Fingerprint) {
+			assert.Equal(t, fingerprint.Compute("Home", fingerprint.DefaultShingleSize), *results[0].Fingerprint)
+			results[0].Fingerprint = nil
+		}
+		// Fetch duration varies
+		results[0].FetchDuration = 0
+		assert.Equal(t, []Result{
+			{
+				Link:       "http://example.com/",
+				StatusCode: 200,
+				BodySize:   int64(len(html)),
+				Links:      []string{"http://example.com/"},
+				PageLinks: []page_parser.Link{
+					{
+						URL:      "http://example.com/",
+						Source:   page_parser.SourceAnchor,
+						Text:     "Home",
+						Position: page_parser.PositionBody,
+					},
+				},
+				NoIndex: true,
+				Text:    "Home",
+				Metadata: &page_parser.PageMetadata{
+					Title:  "Home page",
+					Robots: "noindex",
+				},
+			},
+		}, results)
+	}
+}
+
 func TestCrawlerIncompleteBuild1(t *testing.T) {
 	c := New(nil, nil, nil)
 	assert.Error(t, c.Run(""))
@@ -142,6 +204,54 @@ func TestCrawler_Run_Error(t *testing.T) {
 	}
 }
 
+func TestCrawler_Logger(t *testing.T) {
+	var logs bytes.Buffer
+	c := New(&testFetcher{statusCode: 404}, tFilter, nil).
+		Logger(slog.New(slog.NewJSONHandler(&logs, nil)))
+	assert.NoError(t, c.Run("http://example.com/"))
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if assert.NoError(t, json.Unmarshal(line, &record)) {
+			delete(record, "time")
+			delete(record, "duration")
+			delete(record, "error")
+			records = append(records, record)
+		}
+	}
+	// Per-link debug records are not logged at the default level
+	assert.Equal(t, []map[string]any{
+		{"level": "WARN", "msg": "Results callback function not set"},
+		{"level": "INFO", "msg": "Starting crawl", "url": "http://example.com/"},
+		{"level": "ERROR", "msg": "Page failed", "url": "http://example.com/", "host": "example.com", "status": float64(404)},
+		{"level": "INFO", "msg": "Crawl finished", "pages": float64(1)},
+	}, records)
+}
+
+// failingSet fails to record links
+type failingSet struct{}
+
+func (failingSet) Add(string) (bool, error) {
+	return false, errors.New("disk full")
+}
+
+func TestCrawler_VisitedSet(t *testing.T) {
+	fetcher := &siteFetcher{pages: map[string]string{
+		"http://example.com/": `<a href="/a">A</a><a href="/b">B</a>`,
+	}}
+	set := visited_set.NewMap()
+	_, _ = set.Add("http://example.com/a")
+	assert.NoError(t, New(fetcher, tFilter, nil).MaxPages(10).VisitedSet(set).Run("http://example.com/"))
+	assert.ElementsMatch(t, []string{"http://example.com/", "http://example.com/b"}, fetcher.urls())
+	// Links are crawled when the set fails
+	var logs bytes.Buffer
+	fetcher = &siteFetcher{}
+	c := New(fetcher, tFilter, nil).VisitedSet(failingSet{}).Logger(slog.New(slog.NewTextHandler(&logs, nil)))
+	assert.NoError(t, c.Run("http://example.com/"))
+	assert.Equal(t, []string{"http://example.com/"}, fetcher.urls())
+	assert.Contains(t, logs.String(), `level=ERROR msg="Error recording visited link" url=http://example.com/ error="disk full"`)
+}
+
 func TestCrawler_Parse_Error(t *testing.T) {
 	var results []struct {
 		Link  string
@@ -174,6 +284,8 @@ type testFetcher struct {
 	err        error
 	statusCode int
 	nilBody    bool
+	html       string
+	headers    http.Header
 }
 
 func (t testFetcher) Fetch(_ *page_fetcher.Request) (*page_fetcher.Response, error) {
@@ -181,12 +293,15 @@ func (t testFetcher) Fetch(_ *page_fetcher.Request) (*page_fetcher.Response, err
 		return nil, t.err
 	}
 	body := ioutil.NopCloser(bytes.NewBuffer([]byte(testHTML)))
+	if t.html != "" {
+		body = ioutil.NopCloser(bytes.NewBuffer([]byte(t.html)))
+	}
 	if t.nilBody {
 		body = ioutil.NopCloser(bytes.NewBuffer([]byte(`<html><body><aef<eqf>>>qq></body></ht>`)))
 	}
 	return &page_fetcher.Response{
 		StatusCode: t.statusCode,
-		Headers:    nil,
+		Headers:    t.headers,
 		Body:       body,
 	}, nil
 }
@@ -211,3 +326,30 @@ const testHTML = `<!DOCTYPE html>
 	<a href="http://example.com/">Home</a>
 </body>
 </html>`
+
+func TestCrawler_MaxPagesStopsFetching(t *testing.T) {
+	var hits int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			for i := 0; i < 50; i++ {
+				_, _ = fmt.Fprintf(w, `<a href="/page/%d">Page</a>`, i)
+			}
+		}
+	}))
+	defer server.Close()
+	c := New(page_fetcher.NewFetcher(), tFilter, nil).
+		MaxPages(4).
+		MaxParallelRequests(2).
+		ResultHandler(func(Result) {})
+	if assert.NoError(t, c.Run(server.URL+"/")) {
+		// Queued jobs must not go on fetching once the crawl is finished: jobs still waiting
+		// for a request slot get none, Close waits for requests already sent
+		_, _, stopped := c.limiter.state()
+		assert.True(t, stopped)
+		server.Close()
+		assert.LessOrEqual(t, atomic.LoadInt64(&hits), int64(4+2))
+		assert.Equal(t, uint64(4), c.Stats().Visited)
+	}
+}
@@ -0,0 +1,296 @@
+package distributed
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+)
+
+var ErrClosed = errors.New("coordinator is closed")
+
+const (
+	// How long a worker has to report a leased job before it is handed out again
+	DefaultLeaseTimeout = time.Minute
+	// How long lease requests wait for jobs to appear
+	defaultLeaseWait = 5 * time.Second
+)
+
+// Coordinator keeps crawl jobs until workers lease them and passes the pages they report back;
+// it is a crawler.JobQueue, the crawler using it keeps visited links and the filter.
+// Jobs are processed as fast as workers lease them, up to their batch sizes each
+type Coordinator struct {
+	leaseTimeout time.Duration
+	leaseWait    time.Duration
+	settings     []byte // crawl settings shared with workers
+	token        string // bearer token required by the worker API
+	logger       *slog.Logger
+	mu           sync.Mutex           // guards fields below
+	nextID       uint64               // ID of the last job
+	jobs         map[string]*job      // unfinished jobs by ID
+	pending      []string             // IDs of jobs waiting for a worker, the next one first
+	availableC   chan struct{}        // closed once jobs are added or the coordinator is resumed or closed
+	workers      map[string]time.Time // time of the last lease request by worker ID
+	paused       bool
+	closed       bool
+}
+
+// job is a crawl job along with its lease
+type job struct {
+	crawler.Job
+	start    func() bool        // called before the job is leased for the first time
+	done     func(crawler.Page) // receives the first page reported
+	started  bool               // start has accepted the job
+	worker   string             // worker holding the lease, empty while pending
+	deadline time.Time          // lease expiry
+}
+
+// NewCoordinator creates coordinator, leases not reported within leaseTimeout are handed out again
+func NewCoordinator(leaseTimeout time.Duration) *Coordinator {
+	if leaseTimeout <= 0 {
+		leaseTimeout = DefaultLeaseTimeout
+	}
+	return &Coordinator{
+		leaseTimeout: leaseTimeout,
+		leaseWait:    defaultLeaseWait,
+		settings:     []byte("{}"),
+		logger:       slog.Default(),
+		jobs:         make(map[string]*job),
+		availableC:   make(chan struct{}),
+		workers:      make(map[string]time.Time),
+	}
+}
+
+// Settings sets JSON document workers get to configure fetching and parsing, e.g. the crawl config
+func (c *Coordinator) Settings(settings []byte) *Coordinator {
+	c.settings = settings
+	return c
+}
+
+// Token sets bearer token workers must send, see Handler
+func (c *Coordinator) Token(token string) *Coordinator {
+	c.token = token
+	return c
+}
+
+// Logger sets logger for expired leases
+func (c *Coordinator) Logger(logger *slog.Logger) *Coordinator {
+	c.logger = logger
+	return c
+}
+
+// Queue adds the job for workers, see crawler.JobQueue
+func (c *Coordinator) Queue(j crawler.Job, start func() bool, done func(crawler.Page)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		if start() {
+			go done(closedPage(j))
+		}
+		return
+	}
+	c.nextID++
+	id := strconv.FormatUint(c.nextID, 10)
+	c.jobs[id] = &job{Job: j, start: start, done: done}
+	c.pending = append(c.pending, id)
+	c.notify()
+}
+
+// Pause stops or resumes leasing jobs, leased jobs are still reported
+func (c *Coordinator) Pause(paused bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = paused
+	if !paused {
+		c.notify()
+	}
+}
+
+// Close stops handing out jobs, unfinished jobs fail with ErrClosed
+func (c *Coordinator) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	var failed []*job
+	for id, j := range c.jobs {
+		if j.started || j.start() {
+			failed = append(failed, j)
+		}
+		delete(c.jobs, id)
+	}
+	c.pending = nil
+	c.notify()
+	c.mu.Unlock()
+	for _, j := range failed {
+		j.done(closedPage(j.Job))
+	}
+}
+
+// WaitWorkers waits up to timeout for active workers to ask for jobs once the coordinator is closed,
+// so they exit instead of retrying; returns the number of workers not told
+func (c *Coordinator) WaitWorkers(timeout time.Duration) int {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		c.mu.Lock()
+		active := c.activeWorkers(time.Now())
+		availableC := c.availableC
+		c.mu.Unlock()
+		if active == 0 {
+			return 0
+		}
+		select {
+		case <-availableC:
+		case <-time.After(c.leaseWait):
+			// Workers gone silent stop being active after a while
+		case <-timer.C:
+			return active
+		}
+	}
+}
+
+// Lease hands out up to max jobs to the worker, waiting for jobs to appear for a while;
+// expired leases of other workers are handed out first
+func (c *Coordinator) Lease(worker string, max int) ([]Assignment, error) {
+	timer := time.NewTimer(c.leaseWait)
+	defer timer.Stop()
+	for {
+		c.mu.Lock()
+		if c.closed {
+			// The worker knows the crawl is finished
+			delete(c.workers, worker)
+			c.notify()
+			c.mu.Unlock()
+			return nil, ErrClosed
+		}
+		now := time.Now()
+		c.workers[worker] = now
+		c.requeueExpired(now)
+		assignments := c.take(worker, max, now)
+		availableC := c.availableC
+		c.mu.Unlock()
+		if len(assignments) > 0 {
+			return assignments, nil
+		}
+		select {
+		case <-availableC:
+		case <-timer.C:
+			return nil, nil
+		}
+	}
+}
+
+// Report passes reported pages to the crawler, returns the number of accepted ones;
+// pages of jobs already reported by another worker are ignored
+func (c *Coordinator) Report(worker string, reports []Report) int {
+	c.mu.Lock()
+	finished := make([]*job, 0, len(reports))
+	pages := make([]crawler.Page, 0, len(reports))
+	for _, r := range reports {
+		j, ok := c.jobs[r.ID]
+		if !ok {
+			continue
+		}
+		delete(c.jobs, r.ID)
+		r.Page.Link = j.Link
+		finished, pages = append(finished, j), append(pages, r.Page)
+	}
+	c.mu.Unlock()
+	// The crawler may be queueing jobs meanwhile
+	for i, j := range finished {
+		j.done(pages[i])
+	}
+	return len(finished)
+}
+
+// Stats is a snapshot of the jobs state
+type Stats struct {
+	// Workers asked for jobs within the lease timeout
+	Workers int `json:"workers"`
+	// Jobs waiting for a worker
+	Pending int `json:"pending"`
+	// Jobs being processed by workers
+	Leased int `json:"leased"`
+}
+
+// Stats returns jobs state, safe to call concurrently
+func (c *Coordinator) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := Stats{Workers: c.activeWorkers(time.Now())}
+	for _, j := range c.jobs {
+		if j.worker == "" {
+			stats.Pending++
+		} else {
+			stats.Leased++
+		}
+	}
+	return stats
+}
+
+// activeWorkers returns the number of workers asked for jobs within the lease timeout
+func (c *Coordinator) activeWorkers(now time.Time) int {
+	n := 0
+	for _, seen := range c.workers {
+		if now.Sub(seen) < c.leaseTimeout {
+			n++
+		}
+	}
+	return n
+}
+
+// take leases pending jobs to the worker unless paused, jobs the crawler does not start are dropped
+func (c *Coordinator) take(worker string, max int, now time.Time) []Assignment {
+	var assignments []Assignment
+	for !c.paused && len(c.pending) > 0 && len(assignments) < max {
+		id := c.pending[0]
+		c.pending = c.pending[1:]
+		j, ok := c.jobs[id]
+		if !ok || j.worker != "" {
+			// Reported while waiting for another lease
+			continue
+		}
+		if !j.started {
+			if !j.start() {
+				delete(c.jobs, id)
+				continue
+			}
+			j.started = true
+		}
+		j.worker, j.deadline = worker, now.Add(c.leaseTimeout)
+		assignments = append(assignments, Assignment{ID: id, Job: j.Job, Deadline: j.deadline})
+	}
+	return assignments
+}
+
+// requeueExpired puts jobs with expired leases at the front of pending ones
+func (c *Coordinator) requeueExpired(now time.Time) {
+	var expired []string
+	for id, j := range c.jobs {
+		if j.worker != "" && now.After(j.deadline) {
+			c.logger.Warn("Lease expired, job handed out again", "url", j.Link, "worker", j.worker)
+			j.worker = ""
+			expired = append(expired, id)
+		}
+	}
+	if len(expired) > 0 {
+		c.pending = append(expired, c.pending...)
+	}
+}
+
+// notify wakes up lease requests waiting for jobs and WaitWorkers calls
+func (c *Coordinator) notify() {
+	close(c.availableC)
+	c.availableC = make(chan struct{})
+}
+
+// closedPage is the outcome of a job not processed because the coordinator has been closed
+func closedPage(j crawler.Job) crawler.Page {
+	return crawler.Page{Link: j.Link, Error: &crawler.PageError{Message: ErrClosed.Error(), Class: "other"}}
+}
@@ -0,0 +1,174 @@
+package distributed
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+)
+
+func newTestCoordinator(leaseTimeout time.Duration) *Coordinator {
+	c := NewCoordinator(leaseTimeout)
+	c.leaseWait = 10 * time.Millisecond
+	return c
+}
+
+// queue queues the job, the page is sent into the returned channel
+func queue(c *Coordinator, link string) chan crawler.Page {
+	pageC := make(chan crawler.Page, 1)
+	c.Queue(crawler.Job{Link: link}, func() bool {
+		return true
+	}, func(page crawler.Page) {
+		pageC <- page
+	})
+	return pageC
+}
+
+func TestCoordinator_LeaseReport(t *testing.T) {
+	c := newTestCoordinator(time.Minute)
+	jobs, err := c.Lease("w1", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, jobs, "no jobs yet")
+	pageC := queue(c, "http://example.com/")
+	assert.Equal(t, 1, c.Stats().Pending)
+	jobs, err = c.Lease("w1", 10)
+	if assert.NoError(t, err) && assert.Len(t, jobs, 1) {
+		assert.Equal(t, "http://example.com/", jobs[0].Link)
+		assert.Equal(t, Stats{Workers: 1, Leased: 1}, c.Stats())
+		assert.Equal(t, 0, c.Report("w1", []Report{{ID: "unknown"}}))
+		assert.Equal(t, 1, c.Report("w1", []Report{{ID: jobs[0].ID, Page: crawler.Page{StatusCode: 200}}}))
+		assert.Equal(t, crawler.Page{Link: "http://example.com/", StatusCode: 200}, <-pageC)
+		assert.Equal(t, 0, c.Report("w1", []Report{{ID: jobs[0].ID}}), "reported already")
+	}
+}
+
+func TestCoordinator_LeaseExpiry(t *testing.T) {
+	c := newTestCoordinator(20 * time.Millisecond)
+	pageC := queue(c, "http://example.com/")
+	var jobs []Assignment
+	assert.Eventually(t, func() bool {
+		jobs, _ = c.Lease("dead", 1)
+		return len(jobs) == 1
+	}, time.Second, time.Millisecond)
+	again, err := c.Lease("alive", 1)
+	assert.NoError(t, err)
+	assert.Empty(t, again, "the lease has not expired yet")
+	time.Sleep(30 * time.Millisecond)
+	again, err = c.Lease("alive", 1)
+	if assert.NoError(t, err) && assert.Len(t, again, 1) {
+		assert.Equal(t, jobs[0].ID, again[0].ID)
+		assert.Equal(t, 1, c.Report("alive", []Report{{ID: again[0].ID, Page: crawler.Page{StatusCode: 200}}}))
+		assert.Equal(t, 200, (<-pageC).StatusCode)
+		assert.Equal(t, 0, c.Report("dead", []Report{{ID: jobs[0].ID}}))
+	}
+}
+
+func TestCoordinator_Close(t *testing.T) {
+	c := newTestCoordinator(time.Minute)
+	pageC := queue(c, "http://example.com/")
+	c.Close()
+	c.Close()
+	page := <-pageC
+	if assert.NotNil(t, page.Error) {
+		assert.Equal(t, ErrClosed.Error(), page.Error.Error())
+	}
+	_, err := c.Lease("w1", 1)
+	assert.Equal(t, ErrClosed, err)
+	assert.NotNil(t, (<-queue(c, "http://example.com/")).Error)
+}
+
+func TestCoordinator_PauseStart(t *testing.T) {
+	c := newTestCoordinator(time.Minute)
+	pageC := queue(c, "http://example.com/a")
+	started := 0
+	c.Queue(crawler.Job{Link: "http://example.com/excluded"}, func() bool {
+		started++
+		return false
+	}, func(crawler.Page) {
+		assert.Fail(t, "dropped job reported")
+	})
+	c.Pause(true)
+	jobs, err := c.Lease("w1", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, jobs, "paused")
+	assert.Equal(t, 0, started)
+	leasedC := make(chan []Assignment)
+	go func() {
+		jobs, _ := c.Lease("w1", 10)
+		leasedC <- jobs
+	}()
+	c.Pause(false)
+	jobs = <-leasedC
+	if assert.Len(t, jobs, 1) {
+		assert.Equal(t, "http://example.com/a", jobs[0].Link)
+	}
+	assert.Equal(t, 1, started)
+	assert.Equal(t, Stats{Workers: 1, Leased: 1}, c.Stats(), "the job not started is dropped")
+	c.Report("w1", []Report{{ID: jobs[0].ID, Page: crawler.Page{StatusCode: 200}}})
+	assert.Equal(t, 200, (<-pageC).StatusCode)
+}
+
+func TestCoordinator_Handler(t *testing.T) {
+	c := newTestCoordinator(time.Minute).Settings([]byte(`{"user_agent":"Bot"}`)).Token("secret")
+	server := httptest.NewServer(c.Handler())
+	defer server.Close()
+	testCases := []struct {
+		method, path, body string
+		status             int
+		response           string
+	}{
+		{method: http.MethodGet, path: "/settings", status: http.StatusOK, response: `{"user_agent":"Bot"}`},
+		{method: http.MethodPost, path: "/lease", body: `{"worker":"w1","max_jobs":1}`, status: http.StatusOK, response: `{"jobs":[]}` + "\n"},
+		{method: http.MethodPost, path: "/lease", body: `{"worker":"w1"}`, status: http.StatusBadRequest,
+			response: `{"error":"worker and positive max_jobs required"}` + "\n"},
+		{method: http.MethodPost, path: "/report", body: `{"worker":"w1","reports":[{"id":"1","page":{"link":"x"}}]}`,
+			status: http.StatusOK, response: `{"accepted":0}` + "\n"},
+		{method: http.MethodGet, path: "/stats", status: http.StatusOK, response: `{"workers":1,"pending":0,"leased":0}` + "\n"},
+	}
+	for _, tt := range testCases {
+		req, _ := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if assert.NoError(t, err, tt.path) {
+			assert.Equal(t, tt.status, resp.StatusCode, tt.path)
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			assert.Equal(t, tt.response, string(body), tt.path)
+		}
+	}
+	resp, err := http.Get(server.URL + "/settings")
+	if assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "no token")
+	}
+	c.Close()
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/lease", strings.NewReader(`{"worker":"w1","max_jobs":1}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	if assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusGone, resp.StatusCode)
+	}
+}
+
+func TestCoordinator_WaitWorkers(t *testing.T) {
+	c := newTestCoordinator(50 * time.Millisecond)
+	_, _ = c.Lease("w1", 1)
+	_, _ = c.Lease("w2", 1)
+	c.Close()
+	assert.Equal(t, 2, c.WaitWorkers(time.Millisecond))
+	go func() {
+		_, _ = c.Lease("w1", 1)
+	}()
+	// w2 is not active once the lease timeout passes
+	assert.Equal(t, 0, c.WaitWorkers(time.Second))
+	assert.Equal(t, Stats{}, c.Stats())
+}
@@ -0,0 +1,88 @@
+package distributed
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/json_api"
+)
+
+// Assignment is a job leased to a worker
+type Assignment struct {
+	ID string `json:"id"`
+	crawler.Job
+	// The job is handed out again if not reported by this time
+	Deadline time.Time `json:"deadline"`
+}
+
+// Report is a page processed by a worker
+type Report struct {
+	ID   string       `json:"id"`
+	Page crawler.Page `json:"page"`
+}
+
+type leaseRequest struct {
+	Worker  string `json:"worker"`
+	MaxJobs int    `json:"max_jobs"`
+}
+
+type leaseResponse struct {
+	Jobs []Assignment `json:"jobs"`
+}
+
+type reportRequest struct {
+	Worker  string   `json:"worker"`
+	Reports []Report `json:"reports"`
+}
+
+type reportResponse struct {
+	Accepted int `json:"accepted"`
+}
+
+// Handler returns HTTP API for workers, requests and responses are JSON:
+//
+//	GET  /settings  crawl settings shared by the coordinator
+//	GET  /stats     number of workers, pending and leased jobs
+//	POST /lease     {"worker": "host-1", "max_jobs": 10}, responds with {"jobs": [...]}, possibly empty
+//	POST /report    {"worker": "host-1", "reports": [{"id": "1", "page": {...}}]}, responds with {"accepted": 1}
+//
+// Lease requests get 410 Gone once the coordinator is closed, errors are reported as {"error": "..."}.
+// If the token is set, requests must have "Authorization: Bearer <token>" header
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /settings", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(c.settings)
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		json_api.Respond(w, http.StatusOK, c.Stats())
+	})
+	mux.HandleFunc("POST /lease", func(w http.ResponseWriter, r *http.Request) {
+		var req leaseRequest
+		if !json_api.Decode(w, r, &req) {
+			return
+		}
+		if req.Worker == "" || req.MaxJobs < 1 {
+			json_api.Respond(w, http.StatusBadRequest, json_api.ErrorResponse{Error: "worker and positive max_jobs required"})
+			return
+		}
+		jobs, err := c.Lease(req.Worker, req.MaxJobs)
+		if errors.Is(err, ErrClosed) {
+			json_api.Respond(w, http.StatusGone, json_api.ErrorResponse{Error: err.Error()})
+			return
+		}
+		if jobs == nil {
+			jobs = []Assignment{}
+		}
+		json_api.Respond(w, http.StatusOK, leaseResponse{Jobs: jobs})
+	})
+	mux.HandleFunc("POST /report", func(w http.ResponseWriter, r *http.Request) {
+		var req reportRequest
+		if json_api.Decode(w, r, &req) {
+			json_api.Respond(w, http.StatusOK, reportResponse{Accepted: c.Report(req.Worker, req.Reports)})
+		}
+	})
+	return json_api.Authorize(c.token, mux)
+}
@@ -0,0 +1,198 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/json_api"
+)
+
+const (
+	// Jobs leased and processed in parallel at once
+	DefaultBatchSize = 10
+	// Consecutive failed requests to the coordinator before the worker gives up
+	defaultMaxFailures = 10
+	// Pause after a failed request
+	defaultRetryDelay = time.Second
+	// Requests time out after this, lease requests wait for jobs for a while
+	requestTimeout = time.Minute
+)
+
+// Worker leases jobs from the coordinator, processes them and reports pages back
+type Worker struct {
+	baseURL     string // coordinator URL
+	token       string // bearer token of the coordinator API
+	processor   crawler.PageProcessor
+	id          string
+	batchSize   int
+	maxFailures int
+	retryDelay  time.Duration
+	client      *http.Client
+	logger      *slog.Logger
+}
+
+// NewWorker creates worker processing jobs of the coordinator at the URL with the processor, e.g. *crawler.Crawler
+func NewWorker(coordinatorURL string, processor crawler.PageProcessor) *Worker {
+	host, _ := os.Hostname()
+	return &Worker{
+		baseURL:     strings.TrimSuffix(coordinatorURL, "/"),
+		processor:   processor,
+		id:          fmt.Sprintf("%s-%d", host, os.Getpid()),
+		batchSize:   DefaultBatchSize,
+		maxFailures: defaultMaxFailures,
+		retryDelay:  defaultRetryDelay,
+		client:      &http.Client{Timeout: requestTimeout},
+		logger:      slog.Default(),
+	}
+}
+
+// ID sets worker name reported to the coordinator, host name and process ID by default
+func (w *Worker) ID(id string) *Worker {
+	w.id = id
+	return w
+}
+
+// Token sets bearer token the coordinator requires
+func (w *Worker) Token(token string) *Worker {
+	w.token = token
+	return w
+}
+
+// BatchSize sets the number of jobs leased and processed in parallel at once
+func (w *Worker) BatchSize(n int) *Worker {
+	if n > 0 {
+		w.batchSize = n
+	}
+	return w
+}
+
+// Logger sets logger for coordinator request failures
+func (w *Worker) Logger(logger *slog.Logger) *Worker {
+	w.logger = logger
+	return w
+}
+
+// Settings fetches crawl settings shared by the coordinator at the URL, the token is sent if set
+func Settings(ctx context.Context, coordinatorURL, token string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(coordinatorURL, "/")+"/settings", nil)
+	if err != nil {
+		return nil, err
+	}
+	authorize(req, token)
+	resp, err := (&http.Client{Timeout: requestTimeout}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Run processes jobs until the coordinator is closed, returns error if the context is cancelled
+// or the coordinator stays unreachable; jobs not reported are handed out again once their leases expire
+func (w *Worker) Run(ctx context.Context) error {
+	failures := 0
+	for {
+		var resp leaseResponse
+		err := w.post(ctx, "/lease", leaseRequest{Worker: w.id, MaxJobs: w.batchSize}, &resp)
+		switch {
+		case err == nil:
+			failures = 0
+		case errors.Is(err, ErrClosed):
+			return nil
+		case ctx.Err() != nil:
+			return ctx.Err()
+		default:
+			if failures++; failures >= w.maxFailures {
+				return fmt.Errorf("coordinator unreachable: %w", err)
+			}
+			w.logger.Warn("Error leasing jobs", "error", err)
+			select {
+			case <-time.After(w.retryDelay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+		if len(resp.Jobs) == 0 {
+			continue
+		}
+		reports := w.process(resp.Jobs)
+		var accepted reportResponse
+		if err := w.post(ctx, "/report", reportRequest{Worker: w.id, Reports: reports}, &accepted); err != nil {
+			w.logger.Warn("Error reporting pages", "pages", len(reports), "error", err)
+		} else if accepted.Accepted < len(reports) {
+			w.logger.Warn("Pages reported late, processed by other workers", "pages", len(reports)-accepted.Accepted)
+		}
+	}
+}
+
+// process processes jobs in parallel
+func (w *Worker) process(jobs []Assignment) []Report {
+	reports := make([]Report, len(jobs))
+	var wg sync.WaitGroup
+	for i := range jobs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reports[i] = Report{ID: jobs[i].ID, Page: w.processor.Process(jobs[i].Job)}
+		}(i)
+	}
+	wg.Wait()
+	return reports
+}
+
+// post sends JSON request to the coordinator and decodes JSON response, 410 Gone is reported as ErrClosed
+func (w *Worker) post(ctx context.Context, path string, request, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, w.token)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(response)
+	case http.StatusGone:
+		return ErrClosed
+	}
+	return responseError(resp)
+}
+
+// authorize adds the bearer token to the request if it is set
+func authorize(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// responseError describes unexpected response, with the error message if there is one
+func responseError(resp *http.Response) error {
+	var e json_api.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err == nil && e.Error != "" {
+		return fmt.Errorf("got status code %d: %s", resp.StatusCode, e.Error)
+	}
+	return fmt.Errorf("got status code %d", resp.StatusCode)
+}
@@ -0,0 +1,125 @@
+package distributed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+)
+
+// siteProcessor returns pages linking to each other: / -> /a, /b; /a -> /c
+type siteProcessor struct {
+	mu        sync.Mutex
+	processed []string
+}
+
+func (p *siteProcessor) Process(job crawler.Job) crawler.Page {
+	p.mu.Lock()
+	p.processed = append(p.processed, job.Link)
+	p.mu.Unlock()
+	links := map[string][]string{
+		"http://example.com/":  {"http://example.com/a", "http://example.com/b"},
+		"http://example.com/a": {"http://example.com/c", "http://other.com/"},
+	}[job.Link]
+	return crawler.Page{Link: job.Link, StatusCode: 200, Links: links, FollowLinks: links}
+}
+
+func (p *siteProcessor) links() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	links := append([]string(nil), p.processed...)
+	sort.Strings(links)
+	return links
+}
+
+// sameHostFilter accepts example.com links only
+type sameHostFilter struct{}
+
+func (sameHostFilter) Filter(link string) (string, bool) {
+	return link, strings.HasPrefix(link, "http://example.com/")
+}
+
+func TestWorkers(t *testing.T) {
+	coordinator := newTestCoordinator(50 * time.Millisecond).Token("secret")
+	server := httptest.NewServer(coordinator.Handler())
+	defer server.Close()
+	// Dead worker takes the seed and never reports it
+	deadC := make(chan []Assignment)
+	go func() {
+		var jobs []Assignment
+		for len(jobs) == 0 {
+			jobs, _ = coordinator.Lease("dead", 1)
+		}
+		deadC <- jobs
+	}()
+	var results []string
+	c := crawler.New(nil, sameHostFilter{}, nil).
+		JobQueue(coordinator).
+		MaxPages(10).
+		ResultHandler(func(result crawler.Result) {
+			results = append(results, result.Link)
+		})
+	errC := make(chan error, 1)
+	go func() {
+		errC <- c.Run("http://example.com/")
+	}()
+	assert.Len(t, <-deadC, 1)
+	processors := []*siteProcessor{{}, {}}
+	workerErrC := make(chan error, len(processors))
+	for i, p := range processors {
+		w := NewWorker(server.URL, p).ID(string(rune('a' + i))).Token("secret").BatchSize(2)
+		go func() {
+			workerErrC <- w.Run(context.Background())
+		}()
+	}
+	select {
+	case err := <-errC:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "crawl did not finish")
+	}
+	coordinator.Close()
+	for range processors {
+		assert.NoError(t, <-workerErrC)
+	}
+	processed := append(processors[0].links(), processors[1].links()...)
+	sort.Strings(processed)
+	assert.Equal(t, []string{"http://example.com/", "http://example.com/a", "http://example.com/b", "http://example.com/c"}, processed)
+	sort.Strings(results)
+	assert.Equal(t, []string{"http://example.com/", "http://example.com/a", "http://example.com/b", "http://example.com/c"}, results)
+}
+
+func TestSettings(t *testing.T) {
+	server := httptest.NewServer(NewCoordinator(0).Settings([]byte(`{"user_agent":"Bot"}`)).Token("secret").Handler())
+	defer server.Close()
+	settings, err := Settings(context.Background(), server.URL+"/", "secret")
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"user_agent":"Bot"}`, string(settings))
+	}
+	_, err = Settings(context.Background(), server.URL+"/", "wrong")
+	assert.EqualError(t, err, "got status code 401: bearer token required")
+	_, err = Settings(context.Background(), server.URL+"/missing", "secret")
+	assert.EqualError(t, err, "got status code 404")
+}
+
+func TestWorker_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	w := NewWorker(server.URL, &siteProcessor{})
+	w.maxFailures, w.retryDelay = 2, time.Millisecond
+	err := w.Run(context.Background())
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "coordinator unreachable: "), err.Error())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, w.Run(ctx))
+}
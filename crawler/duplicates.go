@@ -0,0 +1,113 @@
+package crawler
+
+import (
+	"sort"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/fingerprint"
+)
+
+// Default maximum Hamming distance between SimHashes of near-duplicate pages
+const DefaultNearDuplicateDistance = 3
+
+// duplicateIndex finds pages with near-identical content.
+// SimHashes are split into maxDistance+1 bands: hashes within maxDistance bits differ in at most
+// maxDistance bands, so they share at least one and only pages sharing a band are compared.
+// Every original page is kept, the index grows with the crawl
+type duplicateIndex struct {
+	maxDistance int                 // negative disables detection
+	exact       map[string]string   // text hash to the original page
+	bands       []simHashBand       // empty if every page is within maxDistance
+	originals   []indexedPage       // pages not found to be duplicates
+	clusters    map[string][]string // original page to its duplicates
+}
+
+type indexedPage struct {
+	link        string
+	fingerprint fingerprint.Fingerprint
+}
+
+// simHashBand maps a range of SimHash bits to originals having those bits
+type simHashBand struct {
+	shift, width uint
+	originals    map[uint64][]int // band value to indexes of originals
+}
+
+func newDuplicateIndex(maxDistance int) *duplicateIndex {
+	d := duplicateIndex{
+		maxDistance: maxDistance,
+		exact:       make(map[string]string),
+		clusters:    make(map[string][]string),
+	}
+	if maxDistance >= 0 && maxDistance < 64 {
+		bandsN := uint(maxDistance + 1)
+		var shift uint
+		for i := uint(0); i < bandsN; i++ {
+			// Widths differ by one bit at most
+			width := (64 - shift) / (bandsN - i)
+			d.bands = append(d.bands, simHashBand{shift: shift, width: width, originals: make(map[uint64][]int)})
+			shift += width
+		}
+	}
+	return &d
+}
+
+// add checks the page against previously seen ones, returning the original if the page is a near-duplicate
+func (d *duplicateIndex) add(link string, fp fingerprint.Fingerprint) (string, bool) {
+	if d.maxDistance < 0 {
+		return "", false
+	}
+	original, ok := d.exact[fp.Exact]
+	if !ok {
+		original, ok = d.near(fp)
+	}
+	if ok {
+		d.clusters[original] = append(d.clusters[original], link)
+		return original, true
+	}
+	d.exact[fp.Exact] = link
+	for i := range d.bands {
+		value := d.bands[i].value(fp.SimHash)
+		d.bands[i].originals[value] = append(d.bands[i].originals[value], len(d.originals))
+	}
+	d.originals = append(d.originals, indexedPage{link: link, fingerprint: fp})
+	return "", false
+}
+
+// near returns the earliest original within maxDistance of the fingerprint
+func (d *duplicateIndex) near(fp fingerprint.Fingerprint) (string, bool) {
+	if len(d.bands) == 0 {
+		// Any two hashes are within 64 bits
+		if len(d.originals) > 0 {
+			return d.originals[0].link, true
+		}
+		return "", false
+	}
+	found := -1
+	for i := range d.bands {
+		for _, n := range d.bands[i].originals[d.bands[i].value(fp.SimHash)] {
+			if (found < 0 || n < found) && fingerprint.Distance(d.originals[n].fingerprint, fp) <= d.maxDistance {
+				found = n
+			}
+		}
+	}
+	if found < 0 {
+		return "", false
+	}
+	return d.originals[found].link, true
+}
+
+func (b simHashBand) value(hash uint64) uint64 {
+	return hash >> b.shift & (1<<b.width - 1)
+}
+
+// report returns clusters of near-duplicate pages, each starting with the original page
+func (d *duplicateIndex) report() [][]string {
+	clusters := make([][]string, 0, len(d.clusters))
+	for original, duplicates := range d.clusters {
+		clusters = append(clusters, append([]string{original}, duplicates...))
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i][0] < clusters[j][0]
+	})
+	return clusters
+}
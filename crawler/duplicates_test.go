@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"math/bits"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/fingerprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateIndex(t *testing.T) {
+	d := newDuplicateIndex(0)
+	_, ok := d.add("/a", fingerprint.Fingerprint{Exact: "a", SimHash: 0b1111})
+	assert.False(t, ok)
+	original, ok := d.add("/b", fingerprint.Fingerprint{Exact: "a", SimHash: 0b0000})
+	assert.True(t, ok, "exact match")
+	assert.Equal(t, "/a", original)
+	_, ok = d.add("/c", fingerprint.Fingerprint{Exact: "c", SimHash: 0b1110})
+	assert.False(t, ok, "too far")
+	assert.Equal(t, [][]string{{"/a", "/b"}}, d.report())
+	d = newDuplicateIndex(1)
+	_, _ = d.add("/c", fingerprint.Fingerprint{Exact: "c", SimHash: 0b1110})
+	original, ok = d.add("/d", fingerprint.Fingerprint{Exact: "d", SimHash: 0b0110})
+	assert.True(t, ok, "near match")
+	assert.Equal(t, "/c", original)
+	assert.Equal(t, [][]string{{"/c", "/d"}}, d.report())
+	d = newDuplicateIndex(64)
+	_, _ = d.add("/e", fingerprint.Fingerprint{Exact: "e", SimHash: 0})
+	original, ok = d.add("/f", fingerprint.Fingerprint{Exact: "f", SimHash: ^uint64(0)})
+	assert.True(t, ok, "any page is within 64 bits")
+	assert.Equal(t, "/e", original)
+	d = newDuplicateIndex(-1)
+	_, _ = d.add("/g", fingerprint.Fingerprint{Exact: "a"})
+	_, ok = d.add("/h", fingerprint.Fingerprint{Exact: "a"})
+	assert.False(t, ok, "detection disabled")
+}
+
+func TestDuplicateIndex_Bands(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, maxDistance := range []int{0, 3, 7, 20, 63} {
+		d := newDuplicateIndex(maxDistance)
+		var hashes []uint64
+		for i := 0; i < 300; i++ {
+			hash := rnd.Uint64()
+			if i > 0 && rnd.Intn(2) == 0 {
+				// Flip up to maxDistance+1 bits of an earlier hash
+				hash = hashes[rnd.Intn(len(hashes))]
+				for n := rnd.Intn(maxDistance + 2); n > 0; n-- {
+					hash ^= 1 << uint(rnd.Intn(64))
+				}
+			}
+			// The earliest original within the distance is expected, like scanning them all
+			want, wantOK := "", false
+			for _, original := range d.originals {
+				if bits.OnesCount64(original.fingerprint.SimHash^hash) <= maxDistance {
+					want, wantOK = original.link, true
+					break
+				}
+			}
+			link := strconv.Itoa(i)
+			got, ok := d.add(link, fingerprint.Fingerprint{Exact: link, SimHash: hash})
+			assert.Equal(t, wantOK, ok, "distance %d, page %d", maxDistance, i)
+			assert.Equal(t, want, got, "distance %d, page %d", maxDistance, i)
+			hashes = append(hashes, hash)
+		}
+	}
+}
+
+func TestCrawler_SkipNearDuplicateLinks(t *testing.T) {
+	const listing = `<p>Our shoes catalogue with the best prices in town, free delivery and returns within thirty days</p>`
+	fetcher := pagesFetcher{
+		"http://example.com/":                        listing + `<a href="/shoes?sort=price">More</a>`,
+		"http://example.com/shoes?sort=price":        listing + `<a href="/shoes?sort=price&page=2">More</a>`,
+		"http://example.com/shoes?sort=price&page=2": `Second page`,
+	}
+	for _, skip := range []bool{false, true} {
+		var results []Result
+		c := New(fetcher, tFilter, nil).
+			MaxPages(10).
+			NearDuplicateDistance(3).
+			SkipNearDuplicateLinks(skip).
+			ResultHandler(func(result Result) {
+				results = append(results, result)
+			})
+		if err := c.Run("http://example.com/"); assert.NoError(t, err) {
+			if skip {
+				assert.Len(t, results, 2)
+			} else {
+				assert.Len(t, results, 3)
+			}
+			assert.Equal(t, "http://example.com/", results[1].NearDuplicateOf)
+			assert.Equal(t, [][]string{{"http://example.com/", "http://example.com/shoes?sort=price"}}, c.DuplicateReport())
+		}
+	}
+}
+
+func TestCrawler_EmptyPagesNotDuplicates(t *testing.T) {
+	fetcher := pagesFetcher{
+		"http://example.com/":  `<a href="/a"></a><a href="/b"></a>`,
+		"http://example.com/a": `<a href="/c"></a>`,
+		"http://example.com/b": `<a href="/d"></a>`,
+	}
+	var results []Result
+	c := New(fetcher, tFilter, nil).
+		MaxPages(10).
+		SkipNearDuplicateLinks(true).
+		ResultHandler(func(result Result) {
+			results = append(results, result)
+		})
+	if assert.NoError(t, c.Run("http://example.com/")) {
+		assert.Len(t, results, 5, "links of pages without text are followed")
+		assert.Empty(t, c.DuplicateReport())
+		for _, result := range results {
+			assert.Nil(t, result.Fingerprint, result.Link)
+		}
+	}
+}
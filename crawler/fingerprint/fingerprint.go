@@ -0,0 +1,68 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+)
+
+// Number of words in a shingle
+const DefaultShingleSize = 3
+
+// Fingerprint identifies page content
+type Fingerprint struct {
+	// SHA-256 of the normalised text, equal for identical content
+	Exact string
+	// SimHash over text shingles, close for similar content
+	SimHash uint64
+}
+
+// Compute returns fingerprint of the text using shingles of shingleSize words
+func Compute(text string, shingleSize int) Fingerprint {
+	if shingleSize < 1 {
+		shingleSize = DefaultShingleSize
+	}
+	words := strings.Fields(strings.ToLower(text))
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return Fingerprint{
+		Exact:   hex.EncodeToString(sum[:]),
+		SimHash: simHash(words, shingleSize),
+	}
+}
+
+// simHash computes SimHash over word shingles
+func simHash(words []string, shingleSize int) uint64 {
+	if len(words) == 0 {
+		return 0
+	}
+	if len(words) < shingleSize {
+		shingleSize = len(words)
+	}
+	var weights [64]int
+	for i := 0; i+shingleSize <= len(words); i++ {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(strings.Join(words[i:i+shingleSize], " ")))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var hash uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			hash |= 1 << uint(bit)
+		}
+	}
+	return hash
+}
+
+// Distance returns Hamming distance between SimHashes of two fingerprints
+func Distance(a, b Fingerprint) int {
+	return bits.OnesCount64(a.SimHash ^ b.SimHash)
+}
@@ -0,0 +1,34 @@
+package fingerprint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const article = `Faceted navigation produces thousands of URLs with near identical content,
+	for example a list of shoes sorted by price or filtered by color where only a few words differ
+	between pages while the rest of the template including header footer and menus stays the same`
+
+func TestCompute(t *testing.T) {
+	a := Compute(article, DefaultShingleSize)
+	assert.Equal(t, a, Compute(strings.ToUpper(article), 0), "case and whitespace do not matter")
+	assert.Len(t, a.Exact, 64)
+	b := Compute(strings.Replace(article, "price", "name", 1), DefaultShingleSize)
+	assert.NotEqual(t, a.Exact, b.Exact)
+	assert.LessOrEqual(t, Distance(a, b), 10, "similar texts")
+	c := Compute("Completely different text about the weather in spring and the birds singing in the trees", DefaultShingleSize)
+	assert.Greater(t, Distance(a, c), Distance(a, b), "different texts")
+}
+
+func TestCompute_Short(t *testing.T) {
+	assert.Equal(t, uint64(0), Compute("", DefaultShingleSize).SimHash)
+	assert.NotEqual(t, uint64(0), Compute("one two", DefaultShingleSize).SimHash)
+}
+
+func TestDistance(t *testing.T) {
+	assert.Equal(t, 0, Distance(Fingerprint{SimHash: 5}, Fingerprint{SimHash: 5}))
+	assert.Equal(t, 2, Distance(Fingerprint{SimHash: 0b101}, Fingerprint{SimHash: 0b110}))
+	assert.Equal(t, 64, Distance(Fingerprint{SimHash: 0}, Fingerprint{SimHash: ^uint64(0)}))
+}
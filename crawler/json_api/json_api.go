@@ -0,0 +1,51 @@
+package json_api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// ErrorResponse is the body of error responses
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Authorize rejects requests without "Authorization: Bearer <token>" header, if the token is set,
+// and POST requests of content types other than application/json, so browsers cannot forge them
+// with cross-site form submissions
+func Authorize(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			Respond(w, http.StatusUnauthorized, ErrorResponse{"bearer token required"})
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				Respond(w, http.StatusUnsupportedMediaType, ErrorResponse{"application/json content type required"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Decode reads JSON request body, responds with an error and returns false if it is malformed
+func Decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		Respond(w, http.StatusBadRequest, ErrorResponse{err.Error()})
+		return false
+	}
+	return true
+}
+
+// Respond writes JSON response with the status
+func Respond(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,61 @@
+package json_api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorize(t *testing.T) {
+	served := 0
+	h := Authorize("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}))
+	testCases := []struct {
+		name          string
+		method        string
+		authorization string
+		contentType   string
+		status        int
+	}{
+		{name: "no token", method: "POST", contentType: "application/json", status: http.StatusUnauthorized},
+		{name: "wrong token", method: "GET", authorization: "Bearer wrong", status: http.StatusUnauthorized},
+		{name: "form post", method: "POST", authorization: "Bearer secret", contentType: "text/plain", status: http.StatusUnsupportedMediaType},
+		{name: "post", method: "POST", authorization: "Bearer secret", contentType: "application/json; charset=utf-8", status: http.StatusOK},
+		{name: "get", method: "GET", authorization: "Bearer secret", status: http.StatusOK},
+	}
+	for _, tt := range testCases {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		req.Header.Set("Authorization", tt.authorization)
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, tt.status, rec.Code, tt.name)
+		if tt.status == http.StatusUnauthorized {
+			assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"), tt.name)
+		}
+	}
+	assert.Equal(t, 2, served)
+
+	rec := httptest.NewRecorder()
+	Authorize("", http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "no token required if not set")
+}
+
+func TestDecode(t *testing.T) {
+	var v struct {
+		Name string `json:"name"`
+	}
+	rec := httptest.NewRecorder()
+	assert.True(t, Decode(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "a"}`)), &v))
+	assert.Equal(t, "a", v.Name)
+
+	rec = httptest.NewRecorder()
+	assert.False(t, Decode(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"other": 1}`)), &v))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error":"json: unknown field \"other\""}`, rec.Body.String())
+}
@@ -0,0 +1,73 @@
+package crawler
+
+import "sync"
+
+// limiter limits the number of jobs processed at once, the limit may be changed while running;
+// paused limiter lets no jobs start, stopped one turns them all away
+type limiter struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	inUse   int
+	paused  bool
+	stopped bool
+}
+
+func newLimiter(limit int) *limiter {
+	l := limiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return &l
+}
+
+// acquire waits for a free slot, returns false if the limiter has been stopped
+func (l *limiter) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for !l.stopped && (l.paused || l.inUse >= l.limit) {
+		l.cond.Wait()
+	}
+	if l.stopped {
+		return false
+	}
+	l.inUse++
+	return true
+}
+
+// release frees the slot taken by acquire
+func (l *limiter) release() {
+	l.mu.Lock()
+	l.inUse--
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// setLimit changes the number of slots, jobs already running are not affected
+func (l *limiter) setLimit(limit int) {
+	l.mu.Lock()
+	l.limit = limit
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// pause stops or resumes handing out slots
+func (l *limiter) pause(paused bool) {
+	l.mu.Lock()
+	l.paused = paused
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// stop makes all waiting and future acquire calls fail
+func (l *limiter) stop() {
+	l.mu.Lock()
+	l.stopped = true
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// state returns the current settings
+func (l *limiter) state() (limit int, paused, stopped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit, l.paused, l.stopped
+}
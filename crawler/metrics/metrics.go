@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/url_filter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metric names prefix
+const namespace = "crawler"
+
+// Metrics collects crawl metrics and exposes them in Prometheus format
+type Metrics struct {
+	registry      *prometheus.Registry
+	pages         *prometheus.CounterVec   // fetched pages by status class
+	bytes         prometheus.Counter       // response body bytes read
+	fetchDuration *prometheus.HistogramVec // time to response headers by host
+	errors        *prometheus.CounterVec   // page errors by type
+	rejections    *prometheus.CounterVec   // links rejected by the filter by reason
+	robotsDenials *prometheus.CounterVec   // links disallowed by robots.txt by host
+}
+
+// New creates metrics registered in a dedicated registry along with Go runtime and process metrics
+func New() *Metrics {
+	m := Metrics{
+		registry: prometheus.NewRegistry(),
+		pages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pages_fetched_total",
+			Help:      "Pages fetched, by response status class.",
+		}, []string{"status_class"}),
+		bytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "downloaded_bytes_total",
+			Help:      "Response body bytes downloaded.",
+		}),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "fetch_duration_seconds",
+			Help:      "Time to response headers, including redirects, by host.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"host"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Pages failed to be crawled, by error type.",
+		}, []string{"type"}),
+		rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "filter_rejections_total",
+			Help:      "Links rejected by the URL filter, by reason.",
+		}, []string{"reason"}),
+		robotsDenials: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "robots_denials_total",
+			Help:      "Links disallowed by robots.txt, by host.",
+		}, []string{"host"}),
+	}
+	m.registry.MustRegister(
+		m.pages, m.bytes, m.fetchDuration, m.errors, m.rejections, m.robotsDenials,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return &m
+}
+
+// Frontier is a crawl with links waiting to be fetched and being fetched, e.g. *crawler.Crawler
+type Frontier interface {
+	QueueLength() int
+	InFlight() int
+}
+
+// WatchFrontier adds gauges reporting the frontier size and the number of requests in flight
+func (m *Metrics) WatchFrontier(f Frontier) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queued_links",
+			Help:      "Links waiting to be fetched.",
+		}, func() float64 {
+			return float64(f.QueueLength())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "in_flight_requests",
+			Help:      "Pages being fetched and processed.",
+		}, func() float64 {
+			return float64(f.InFlight())
+		}),
+	)
+}
+
+// ObserveResult records page crawl result
+func (m *Metrics) ObserveResult(result crawler.Result) {
+	if result.StatusCode > 0 {
+		m.pages.WithLabelValues(statusClass(result.StatusCode)).Inc()
+		m.fetchDuration.WithLabelValues(url_filter.Host(result.Link)).Observe(result.FetchDuration.Seconds())
+	}
+	m.bytes.Add(float64(result.BodySize))
+	if result.Error != nil {
+		m.errors.WithLabelValues(result.ErrorClass()).Inc()
+	}
+}
+
+// ObserveRejection records link rejected by the URL filter, to be used with NormalizingFilter.OnReject
+func (m *Metrics) ObserveRejection(link string, err error) {
+	m.rejections.WithLabelValues(rejectionReason(err)).Inc()
+	if errors.Is(err, url_filter.ErrRobotsDisallowed) {
+		m.robotsDenials.WithLabelValues(url_filter.Host(link)).Inc()
+	}
+}
+
+// ResultHandler wraps results callback to record every result
+func (m *Metrics) ResultHandler(next func(crawler.Result)) func(crawler.Result) {
+	return func(result crawler.Result) {
+		m.ObserveResult(result)
+		next(result)
+	}
+}
+
+// Handler serves metrics in Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// statusClass returns status class label, e.g. "2xx"
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// rejectionReason returns rejection reason label
+func rejectionReason(err error) string {
+	var ruleErr *url_filter.RuleError
+	switch {
+	case errors.Is(err, url_filter.ErrBadURL):
+		return "bad_url"
+	case errors.Is(err, url_filter.ErrOutOfScope):
+		return "scope"
+	case errors.Is(err, url_filter.ErrSchemeNotAllowed):
+		return "scheme"
+	case errors.Is(err, url_filter.ErrPortNotAllowed):
+		return "port"
+	case errors.Is(err, url_filter.ErrRobotsDisallowed):
+		return "robots"
+	case errors.As(err, &ruleErr):
+		return "rule"
+	case errors.Is(err, url_filter.ErrNotIncluded):
+		return "not_included"
+	}
+	return "other"
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/dmitry-vovk/wcrawler/crawler/url_filter"
+)
+
+type testFrontier struct{}
+
+func (testFrontier) QueueLength() int { return 7 }
+func (testFrontier) InFlight() int    { return 2 }
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.WatchFrontier(testFrontier{})
+	var handled int
+	handler := m.ResultHandler(func(crawler.Result) { handled++ })
+	handler(crawler.Result{Link: "http://example.com/", StatusCode: 200, FetchDuration: 30 * time.Millisecond, BodySize: 1000})
+	handler(crawler.Result{Link: "http://example.com/a", StatusCode: 200, FetchDuration: 2 * time.Second, BodySize: 500})
+	handler(crawler.Result{Link: "http://example.org/b", StatusCode: 404, Error: errors.New("got status code 404")})
+	handler(crawler.Result{Link: "http://example.org/c", Error: pkgerrors.Wrap(page_fetcher.ErrBadContentType, "fetch")})
+	assert.Equal(t, 4, handled)
+	m.ObserveRejection("http://example.com/private", url_filter.ErrRobotsDisallowed)
+	m.ObserveRejection("http://example.net/", url_filter.ErrOutOfScope)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.pages.WithLabelValues("2xx")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.pages.WithLabelValues("4xx")))
+	assert.Equal(t, 1500.0, testutil.ToFloat64(m.bytes))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.errors.WithLabelValues("status")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.errors.WithLabelValues("content_type")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.rejections.WithLabelValues("robots")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.rejections.WithLabelValues("scope")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.robotsDenials.WithLabelValues("example.com")))
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		`crawler_fetch_duration_seconds_bucket{host="example.com",le="0.05"} 1`,
+		`crawler_fetch_duration_seconds_count{host="example.com"} 2`,
+		`crawler_fetch_duration_seconds_count{host="example.org"} 1`,
+		`crawler_queued_links 7`,
+		`crawler_in_flight_requests 2`,
+		`crawler_downloaded_bytes_total 1500`,
+	} {
+		assert.Contains(t, body, line)
+	}
+	assert.True(t, strings.Contains(body, "go_goroutines"), "runtime metrics")
+}
+
+func TestRejectionReason(t *testing.T) {
+	assert.Equal(t, "bad_url", rejectionReason(url_filter.ErrBadURL))
+	assert.Equal(t, "scheme", rejectionReason(url_filter.ErrSchemeNotAllowed))
+	assert.Equal(t, "port", rejectionReason(url_filter.ErrPortNotAllowed))
+	assert.Equal(t, "rule", rejectionReason(&url_filter.RuleError{N: 1}))
+	assert.Equal(t, "not_included", rejectionReason(url_filter.ErrNotIncluded))
+	assert.Equal(t, "other", rejectionReason(errors.New("unknown")))
+}
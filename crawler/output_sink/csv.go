@@ -0,0 +1,66 @@
+package output_sink
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+)
+
+// csvHeader lists CSV columns, links are separated with spaces
+var csvHeader = []string{
+	"url", "status", "error", "canonical_url", "canonical_duplicate", "near_duplicate_of", "noindex", "title", "links",
+}
+
+// headerSetter is implemented by writers starting every file with a header, e.g. rotating File
+type headerSetter interface {
+	SetHeader(header []byte) error
+}
+
+// CSVSink writes a CSV row per page, the header comes first
+type CSVSink struct {
+	w io.Writer
+}
+
+// NewCSVSink returns sink writing records as CSV, the header is written right away
+func NewCSVSink(w io.Writer) (*CSVSink, error) {
+	header := csvRow(csvHeader)
+	if hs, ok := w.(headerSetter); ok {
+		return &CSVSink{w: w}, hs.SetHeader(header)
+	}
+	_, err := w.Write(header)
+	return &CSVSink{w: w}, err
+}
+
+func (s *CSVSink) Write(result crawler.Result) error {
+	r := NewRecord(result, false)
+	_, err := s.w.Write(csvRow([]string{
+		r.URL,
+		strconv.Itoa(r.Status),
+		r.Error,
+		r.CanonicalURL,
+		strconv.FormatBool(r.CanonicalDuplicate),
+		r.NearDuplicateOf,
+		strconv.FormatBool(r.NoIndex),
+		r.Title,
+		strings.Join(r.Links, " "),
+	}))
+	return err
+}
+
+func (s *CSVSink) Close() error {
+	return closeWriter(s.w)
+}
+
+// csvRow encodes a single CSV row
+func csvRow(fields []string) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	// Writing into a buffer never fails
+	_ = w.Write(fields)
+	w.Flush()
+	return buf.Bytes()
+}
@@ -0,0 +1,147 @@
+package output_sink
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Open returns writer for the output file, empty path stands for stdout, which is never closed
+func Open(path string, maxSize int64, compress bool, stdout io.Writer) (io.WriteCloser, error) {
+	if path == "" {
+		return nopCloser{stdout}, nil
+	}
+	return OpenFile(path, maxSize, compress)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// File writes into a file, moving it aside and starting a new one when the size limit is reached:
+// results.jsonl.gz becomes results.1.jsonl.gz, then results.2.jsonl.gz and so on
+type File struct {
+	path     string
+	maxSize  int64 // uncompressed size limit, 0 for no rotation
+	compress bool
+	header   []byte // written at the start of every file
+	file     *os.File
+	gz       *gzip.Writer
+	size     int64 // bytes written into the current file before compression
+	rotated  int   // number of the last rotated file
+}
+
+// OpenFile creates or truncates the file, maxSize of 0 disables rotation
+func OpenFile(path string, maxSize int64, compress bool) (*File, error) {
+	f := File{path: path, maxSize: maxSize, compress: compress}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// SetHeader sets data every file starts with, it is written right away if the file is empty
+func (f *File) SetHeader(header []byte) error {
+	f.header = header
+	if f.size > 0 {
+		return nil
+	}
+	_, err := f.write(header)
+	return err
+}
+
+// Write writes p into the current file, p is never split between files
+func (f *File) Write(p []byte) (int, error) {
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > int64(len(f.header)) && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	return f.write(p)
+}
+
+func (f *File) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	var err error
+	if f.gz != nil {
+		err = f.gz.Close()
+	}
+	err = errors.Join(err, f.file.Close())
+	f.file, f.gz = nil, nil
+	return err
+}
+
+func (f *File) open() error {
+	file, err := os.Create(f.path)
+	if err != nil {
+		return err
+	}
+	f.file, f.size = file, 0
+	if f.compress {
+		f.gz = gzip.NewWriter(file)
+	}
+	return nil
+}
+
+func (f *File) write(p []byte) (int, error) {
+	var w io.Writer = f.file
+	if f.gz != nil {
+		w = f.gz
+	}
+	n, err := w.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate closes the current file, renames it and opens a new one starting with the header
+func (f *File) rotate() error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	name, err := f.nextName()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(f.path, name); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	if len(f.header) > 0 {
+		_, err = f.write(f.header)
+	}
+	return err
+}
+
+// nextName returns the first unused rotated file name, numbered after the last one
+func (f *File) nextName() (string, error) {
+	dir, base := filepath.Split(f.path)
+	stem, ext, _ := strings.Cut(base, ".")
+	if ext != "" {
+		ext = "." + ext
+	}
+	for {
+		f.rotated++
+		name := filepath.Join(dir, fmt.Sprintf("%s.%d%s", stem, f.rotated, ext))
+		if _, err := os.Stat(name); errors.Is(err, fs.ErrNotExist) {
+			return name, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
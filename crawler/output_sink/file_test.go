@@ -0,0 +1,80 @@
+package output_sink
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readFile(t *testing.T, name string, compressed bool) string {
+	f, err := os.Open(name)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	var r io.Reader = f
+	if compressed {
+		gz, err := gzip.NewReader(f)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		r = gz
+	}
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestFile_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	// Existing rotated files are kept
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "out.1.txt"), []byte("old"), 0o600))
+	f, err := OpenFile(filepath.Join(dir, "out.txt"), 10, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, s := range []string{"aaaa\n", "bbbb\n", "cccccccccccc\n", "dd\n"} {
+		n, err := f.Write([]byte(s))
+		assert.NoError(t, err)
+		assert.Equal(t, len(s), n)
+	}
+	assert.NoError(t, f.Close())
+	_, err = f.Write([]byte("late"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+	assert.Equal(t, "old", readFile(t, filepath.Join(dir, "out.1.txt"), false))
+	assert.Equal(t, "aaaa\nbbbb\n", readFile(t, filepath.Join(dir, "out.2.txt"), false))
+	// Records larger than the limit get a file of their own
+	assert.Equal(t, "cccccccccccc\n", readFile(t, filepath.Join(dir, "out.3.txt"), false))
+	assert.Equal(t, "dd\n", readFile(t, filepath.Join(dir, "out.txt"), false))
+}
+
+func TestFile_CSVGzip(t *testing.T) {
+	dir := t.TempDir()
+	f, err := OpenFile(filepath.Join(dir, "out.csv.gz"), 200, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	sink, err := NewCSVSink(f)
+	if assert.NoError(t, err) {
+		writeAll(t, sink)
+	}
+	header := "url,status,error,canonical_url,canonical_duplicate,near_duplicate_of,noindex,title,links\n"
+	assert.Equal(t, header+"http://example.com/,200,,http://example.com/home,false,,true,\"Home, sweet home\",http://example.com/a http://example.com/b\n",
+		readFile(t, filepath.Join(dir, "out.1.csv.gz"), true))
+	assert.Equal(t, header+"http://example.com/missing,404,got status code 404,,false,,false,,\n",
+		readFile(t, filepath.Join(dir, "out.csv.gz"), true))
+}
+
+func TestOpen_Stdout(t *testing.T) {
+	w, err := Open("", 0, false, os.Stdout)
+	if assert.NoError(t, err) {
+		assert.Equal(t, nopCloser{os.Stdout}, w)
+		assert.NoError(t, w.Close())
+	}
+}
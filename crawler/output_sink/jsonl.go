@@ -0,0 +1,32 @@
+package output_sink
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+)
+
+// JSONLinesSink writes a JSON record per line, see https://jsonlines.org/
+type JSONLinesSink struct {
+	w            io.Writer
+	withMetadata bool
+}
+
+// NewJSONLinesSink returns sink writing records as JSON Lines
+func NewJSONLinesSink(w io.Writer, withMetadata bool) *JSONLinesSink {
+	return &JSONLinesSink{w: w, withMetadata: withMetadata}
+}
+
+func (s *JSONLinesSink) Write(result crawler.Result) error {
+	line, err := json.Marshal(NewRecord(result, s.withMetadata))
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+func (s *JSONLinesSink) Close() error {
+	return closeWriter(s.w)
+}
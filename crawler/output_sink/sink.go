@@ -0,0 +1,89 @@
+package output_sink
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_parser"
+)
+
+// Output formats
+const (
+	FormatText      = "text"
+	FormatJSONLines = "jsonl"
+	FormatCSV       = "csv"
+)
+
+// OutputSink receives crawl results one page at a time
+type OutputSink interface {
+	// Write outputs the result of a single page crawl
+	Write(result crawler.Result) error
+	// Close flushes buffered output and closes the underlying writer
+	Close() error
+}
+
+// New returns sink writing results to w in the given format, empty format stands for text;
+// metadata is included in text and JSON Lines output only
+func New(format string, w io.Writer, withMetadata bool) (OutputSink, error) {
+	switch format {
+	case "", FormatText:
+		return NewTextSink(w, withMetadata), nil
+	case FormatJSONLines:
+		return NewJSONLinesSink(w, withMetadata), nil
+	case FormatCSV:
+		return NewCSVSink(w)
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// Record is a machine-readable page crawl outcome
+type Record struct {
+	URL                string                    `json:"url"`
+	Status             int                       `json:"status"`
+	Redirects          []page_fetcher.Redirect   `json:"redirects,omitempty"`
+	Error              string                    `json:"error,omitempty"`
+	CanonicalURL       string                    `json:"canonical_url,omitempty"`
+	CanonicalDuplicate bool                      `json:"canonical_duplicate,omitempty"`
+	NearDuplicateOf    string                    `json:"near_duplicate_of,omitempty"`
+	NoIndex            bool                      `json:"noindex,omitempty"`
+	Title              string                    `json:"title,omitempty"`
+	Links              []string                  `json:"links"`
+	Metadata           *page_parser.PageMetadata `json:"metadata,omitempty"`
+}
+
+// NewRecord converts crawl result into a record
+func NewRecord(result crawler.Result, withMetadata bool) Record {
+	r := Record{
+		URL:                result.Link,
+		Status:             result.StatusCode,
+		Redirects:          result.Redirects,
+		CanonicalURL:       result.CanonicalLink,
+		CanonicalDuplicate: result.CanonicalDuplicate,
+		NearDuplicateOf:    result.NearDuplicateOf,
+		NoIndex:            result.NoIndex,
+		Links:              result.Links,
+	}
+	if result.Error != nil {
+		r.Error = result.Error.Error()
+	}
+	if result.Metadata != nil {
+		r.Title = result.Metadata.Title
+		if withMetadata {
+			r.Metadata = result.Metadata
+		}
+	}
+	if r.Links == nil {
+		r.Links = []string{}
+	}
+	return r
+}
+
+// closeWriter closes w if it is closable
+func closeWriter(w io.Writer) error {
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package output_sink
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var testResults = []crawler.Result{
+	{
+		Link:          "http://example.com/",
+		StatusCode:    200,
+		CanonicalLink: "http://example.com/home",
+		Links:         []string{"http://example.com/a", "http://example.com/b"},
+		NoIndex:       true,
+		Metadata:      &page_parser.PageMetadata{Title: "Home, sweet home"},
+	},
+	{
+		Link:       "http://example.com/missing",
+		StatusCode: 404,
+		Error:      errors.New("got status code 404"),
+	},
+}
+
+func writeAll(t *testing.T, sink OutputSink) {
+	for _, result := range testResults {
+		assert.NoError(t, sink.Write(result))
+	}
+	assert.NoError(t, sink.Close())
+}
+
+func TestNew(t *testing.T) {
+	for format, expected := range map[string]OutputSink{
+		"":              &TextSink{},
+		FormatText:      &TextSink{},
+		FormatJSONLines: &JSONLinesSink{},
+		FormatCSV:       &CSVSink{},
+	} {
+		sink, err := New(format, &bytes.Buffer{}, false)
+		if assert.NoError(t, err, format) {
+			assert.IsType(t, expected, sink, format)
+		}
+	}
+	_, err := New("xml", &bytes.Buffer{}, false)
+	assert.EqualError(t, err, `unknown output format "xml"`)
+}
+
+func TestTextSink(t *testing.T) {
+	var buf bytes.Buffer
+	writeAll(t, NewTextSink(&buf, true))
+	assert.Equal(t, "Links found on the page http://example.com/ (noindex)\n"+
+		"Metadata: {\"title\":\"Home, sweet home\"}\n"+
+		"\thttp://example.com/a\n"+
+		"\thttp://example.com/b\n"+
+		"Links found on the page http://example.com/missing\n", buf.String())
+}
+
+func TestJSONLinesSink(t *testing.T) {
+	var buf bytes.Buffer
+	writeAll(t, NewJSONLinesSink(&buf, false))
+	assert.Equal(t, `{"url":"http://example.com/","status":200,"canonical_url":"http://example.com/home",`+
+		`"noindex":true,"title":"Home, sweet home","links":["http://example.com/a","http://example.com/b"]}`+"\n"+
+		`{"url":"http://example.com/missing","status":404,"error":"got status code 404","links":[]}`+"\n", buf.String())
+	buf.Reset()
+	writeAll(t, NewJSONLinesSink(&buf, true))
+	assert.Contains(t, buf.String(), `"metadata":{"title":"Home, sweet home"}`)
+}
+
+func TestCSVSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := NewCSVSink(&buf)
+	if assert.NoError(t, err) {
+		writeAll(t, sink)
+		assert.Equal(t, "url,status,error,canonical_url,canonical_duplicate,near_duplicate_of,noindex,title,links\n"+
+			"http://example.com/,200,,http://example.com/home,false,,true,\"Home, sweet home\",http://example.com/a http://example.com/b\n"+
+			"http://example.com/missing,404,got status code 404,,false,,false,,\n", buf.String())
+	}
+}
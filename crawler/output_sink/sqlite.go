@@ -0,0 +1,220 @@
+package output_sink
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/sqlite_batch"
+	_ "modernc.org/sqlite" // registers "sqlite" database driver
+)
+
+// FormatSQLite stores results in SQLite database file, see OpenSQLite
+const FormatSQLite = "sqlite"
+
+// How many pages are written in a single transaction
+const defaultBatchSize = 100
+
+// sqliteSchema is created in every database, so runs accumulate in the same file
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY,
+	seed_url    TEXT NOT NULL,
+	config      TEXT NOT NULL, -- effective settings as JSON, secrets redacted
+	started_at  TEXT NOT NULL, -- RFC 3339 timestamps
+	finished_at TEXT,
+	pages       INTEGER NOT NULL DEFAULT 0,
+	status      TEXT -- 'finished' or 'interrupted', NULL while the crawl runs
+);
+CREATE TABLE IF NOT EXISTS pages (
+	id                  INTEGER PRIMARY KEY,
+	run_id              INTEGER NOT NULL REFERENCES runs (id),
+	url                 TEXT NOT NULL,
+	status              INTEGER NOT NULL, -- 0 if no response was received
+	canonical_url       TEXT,
+	canonical_duplicate INTEGER NOT NULL,
+	near_duplicate_of   TEXT,
+	noindex             INTEGER NOT NULL,
+	title               TEXT,
+	metadata            TEXT -- JSON
+);
+CREATE INDEX IF NOT EXISTS pages_url ON pages (url, run_id);
+CREATE TABLE IF NOT EXISTS links (
+	page_id  INTEGER NOT NULL REFERENCES pages (id),
+	url      TEXT NOT NULL, -- resolved against the page URL
+	source   TEXT NOT NULL, -- element and attribute, e.g. a[href]
+	text     TEXT,          -- anchor text
+	rel      TEXT,          -- space-separated rel values
+	position TEXT           -- page region: head, nav, header, footer or body
+);
+CREATE INDEX IF NOT EXISTS links_page ON links (page_id);
+CREATE INDEX IF NOT EXISTS links_url ON links (url);
+CREATE TABLE IF NOT EXISTS redirects (
+	page_id  INTEGER NOT NULL REFERENCES pages (id),
+	hop      INTEGER NOT NULL, -- starting with 1
+	from_url TEXT NOT NULL,
+	to_url   TEXT NOT NULL,
+	status   INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS errors (
+	page_id INTEGER NOT NULL REFERENCES pages (id),
+	message TEXT NOT NULL
+);
+`
+
+// Run describes the crawl results belong to
+type Run struct {
+	// Crawl starting URL
+	SeedURL string
+	// Effective crawl settings as JSON
+	Config string
+}
+
+// SQLiteSink writes results into SQLite database in batched transactions,
+// every sink adds a row into runs table, the other tables refer to it
+type SQLiteSink struct {
+	db          *sql.DB
+	runID       int64
+	pages       int64 // pages written in this run
+	batch       *sqlite_batch.Batch
+	interrupted bool // whether the run is recorded as interrupted on close
+}
+
+// OpenSQLite opens or creates the database and starts a new run in it
+func OpenSQLite(path string, run Run) (*SQLiteSink, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// Transactions are sequential anyway, a single connection avoids lock contention
+	db.SetMaxOpenConns(1)
+	s := SQLiteSink{db: db, batch: sqlite_batch.New(db, defaultBatchSize)}
+	if err := s.start(run); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &s, nil
+}
+
+// start creates schema and run record
+func (s *SQLiteSink) start(run Run) error {
+	if _, err := s.db.Exec(sqliteSchema); err != nil {
+		return err
+	}
+	// Databases created by older versions have no run status
+	var hasStatus bool
+	if err := s.db.QueryRow(`SELECT count(*) > 0 FROM pragma_table_info('runs') WHERE name = 'status'`).Scan(&hasStatus); err != nil {
+		return err
+	}
+	if !hasStatus {
+		if _, err := s.db.Exec(`ALTER TABLE runs ADD COLUMN status TEXT`); err != nil {
+			return err
+		}
+	}
+	res, err := s.db.Exec(`INSERT INTO runs (seed_url, config, started_at) VALUES (?, ?, ?)`,
+		run.SeedURL, run.Config, timestamp())
+	if err != nil {
+		return err
+	}
+	s.runID, err = res.LastInsertId()
+	return err
+}
+
+// RunID returns ID of the run results are written to
+func (s *SQLiteSink) RunID() int64 {
+	return s.runID
+}
+
+func (s *SQLiteSink) Write(result crawler.Result) error {
+	tx, err := s.batch.Tx()
+	if err != nil {
+		return err
+	}
+	// Savepoint keeps the rest of the batch when a page fails to be written
+	if _, err := tx.Exec(`SAVEPOINT page`); err != nil {
+		return err
+	}
+	if err := s.insert(tx, result); err != nil {
+		_, _ = tx.Exec(`ROLLBACK TO page`)
+		_, _ = tx.Exec(`RELEASE page`)
+		return err
+	}
+	if _, err := tx.Exec(`RELEASE page`); err != nil {
+		return err
+	}
+	s.pages++
+	return s.batch.Done()
+}
+
+// insert adds page with its links, redirects and error into the transaction
+func (s *SQLiteSink) insert(tx *sql.Tx, result crawler.Result) error {
+	r := NewRecord(result, true)
+	var metadata sql.NullString
+	if r.Metadata != nil {
+		// Metadata contains only JSON-compatible values
+		data, _ := json.Marshal(r.Metadata)
+		metadata = sql.NullString{String: string(data), Valid: true}
+	}
+	res, err := tx.Exec(`INSERT INTO pages
+		(run_id, url, status, canonical_url, canonical_duplicate, near_duplicate_of, noindex, title, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.runID, r.URL, r.Status, nullString(r.CanonicalURL), r.CanonicalDuplicate,
+		nullString(r.NearDuplicateOf), r.NoIndex, nullString(r.Title), metadata)
+	if err != nil {
+		return err
+	}
+	pageID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, link := range result.PageLinks {
+		if _, err := tx.Exec(`INSERT INTO links (page_id, url, source, text, rel, position) VALUES (?, ?, ?, ?, ?, ?)`,
+			pageID, link.URL, string(link.Source), nullString(link.Text),
+			nullString(strings.Join(link.Rel, " ")), nullString(string(link.Position))); err != nil {
+			return err
+		}
+	}
+	for i, redirect := range r.Redirects {
+		if _, err := tx.Exec(`INSERT INTO redirects (page_id, hop, from_url, to_url, status) VALUES (?, ?, ?, ?, ?)`,
+			pageID, i+1, redirect.From, redirect.To, redirect.StatusCode); err != nil {
+			return err
+		}
+	}
+	if r.Error != "" {
+		if _, err := tx.Exec(`INSERT INTO errors (page_id, message) VALUES (?, ?)`, pageID, r.Error); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarkInterrupted records the run as interrupted rather than finished on close
+func (s *SQLiteSink) MarkInterrupted() {
+	s.interrupted = true
+}
+
+// Close commits pending results, records the run outcome and closes the database
+func (s *SQLiteSink) Close() error {
+	err := s.batch.Commit()
+	status := "finished"
+	if s.interrupted {
+		status = "interrupted"
+	}
+	if _, e := s.db.Exec(`UPDATE runs SET finished_at = ?, pages = ?, status = ? WHERE id = ?`,
+		timestamp(), s.pages, status, s.runID); e != nil {
+		err = errors.Join(err, e)
+	}
+	return errors.Join(err, s.db.Close())
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
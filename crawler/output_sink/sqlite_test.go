@@ -0,0 +1,125 @@
+package output_sink
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_parser"
+	"github.com/dmitry-vovk/wcrawler/crawler/sqlite_batch"
+	"github.com/stretchr/testify/assert"
+)
+
+func queryRows(t *testing.T, db *sql.DB, query string) [][]any {
+	rows, err := db.Query(query)
+	if !assert.NoError(t, err, query) {
+		return nil
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	columns, _ := rows.Columns()
+	var result [][]any
+	for rows.Next() {
+		row := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if assert.NoError(t, rows.Scan(pointers...)) {
+			result = append(result, row)
+		}
+	}
+	assert.NoError(t, rows.Err())
+	return result
+}
+
+func TestSQLiteSink(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "crawl.db")
+	results := append([]crawler.Result{{
+		Link:       "http://example.com/new",
+		StatusCode: 200,
+		Redirects: []page_fetcher.Redirect{
+			{From: "http://example.com/old", To: "http://example.com/new", StatusCode: 301},
+		},
+		PageLinks: []page_parser.Link{
+			{URL: "http://example.com/", Source: page_parser.SourceAnchor, Text: "Home", Rel: []string{"nofollow", "me"}, Position: page_parser.PositionNav},
+			{URL: "http://example.com/a", Source: page_parser.SourceArea},
+		},
+	}}, testResults...)
+	for run := 1; run <= 2; run++ {
+		sink, err := OpenSQLite(dbPath, Run{SeedURL: "http://example.com/", Config: `{"max_pages":10}`})
+		if !assert.NoError(t, err) {
+			return
+		}
+		// Batches of two pages make sure pending pages are committed on close
+		sink.batch = sqlite_batch.New(sink.db, 2)
+		assert.Equal(t, int64(run), sink.RunID())
+		for _, result := range results {
+			assert.NoError(t, sink.Write(result))
+		}
+		if run == 2 {
+			sink.MarkInterrupted()
+		}
+		assert.NoError(t, sink.Close())
+	}
+	db, err := sql.Open("sqlite", dbPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	assert.Equal(t, [][]any{
+		{int64(1), "http://example.com/", `{"max_pages":10}`, int64(3), int64(1), "finished"},
+		{int64(2), "http://example.com/", `{"max_pages":10}`, int64(3), int64(1), "interrupted"},
+	}, queryRows(t, db, `SELECT id, seed_url, config, pages, finished_at >= started_at, status FROM runs`))
+	assert.Equal(t, [][]any{
+		{int64(1), "http://example.com/new", int64(200), nil, int64(0), nil, int64(0), nil, nil},
+		{int64(1), "http://example.com/", int64(200), "http://example.com/home", int64(0), nil, int64(1), "Home, sweet home", `{"title":"Home, sweet home"}`},
+		{int64(1), "http://example.com/missing", int64(404), nil, int64(0), nil, int64(0), nil, nil},
+	}, queryRows(t, db, `SELECT run_id, url, status, canonical_url, canonical_duplicate, near_duplicate_of, noindex, title, metadata
+		FROM pages WHERE run_id = 1 ORDER BY id`))
+	assert.Equal(t, [][]any{
+		{"http://example.com/new", "http://example.com/", "a[href]", "Home", "nofollow me", "nav"},
+		{"http://example.com/new", "http://example.com/a", "area[href]", nil, nil, nil},
+	}, queryRows(t, db, `SELECT p.url, l.url, l.source, l.text, l.rel, l.position
+		FROM links l JOIN pages p ON p.id = l.page_id WHERE p.run_id = 1 ORDER BY l.rowid`))
+	assert.Equal(t, [][]any{
+		{"http://example.com/new", int64(1), "http://example.com/old", "http://example.com/new", int64(301)},
+	}, queryRows(t, db, `SELECT p.url, r.hop, r.from_url, r.to_url, r.status
+		FROM redirects r JOIN pages p ON p.id = r.page_id WHERE p.run_id = 1`))
+	assert.Equal(t, [][]any{
+		{int64(1), "http://example.com/missing", "got status code 404"},
+		{int64(2), "http://example.com/missing", "got status code 404"},
+	}, queryRows(t, db, `SELECT p.run_id, p.url, e.message FROM errors e JOIN pages p ON p.id = e.page_id ORDER BY p.run_id`))
+}
+
+func TestSQLiteSink_AddsRunStatus(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "crawl.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	// runs table as created before the status column was added
+	_, err = db.Exec(`CREATE TABLE runs (id INTEGER PRIMARY KEY, seed_url TEXT NOT NULL, config TEXT NOT NULL,
+		started_at TEXT NOT NULL, finished_at TEXT, pages INTEGER NOT NULL DEFAULT 0);
+		INSERT INTO runs (seed_url, config, started_at, finished_at) VALUES ('http://example.com/', '{}', '2024-01-01T00:00:00Z', '2024-01-01T00:01:00Z')`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	sink, err := OpenSQLite(dbPath, Run{SeedURL: "http://example.com/", Config: `{}`})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, sink.Close())
+	assert.Equal(t, [][]any{
+		{int64(1), nil},
+		{int64(2), "finished"},
+	}, queryRows(t, db, `SELECT id, status FROM runs ORDER BY id`))
+}
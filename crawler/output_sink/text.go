@@ -0,0 +1,45 @@
+package output_sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+)
+
+// TextSink writes human-readable list of links found on every page
+type TextSink struct {
+	w            io.Writer
+	withMetadata bool
+}
+
+// NewTextSink returns sink writing page links, and optionally metadata, as plain text
+func NewTextSink(w io.Writer, withMetadata bool) *TextSink {
+	return &TextSink{w: w, withMetadata: withMetadata}
+}
+
+func (s *TextSink) Write(result crawler.Result) error {
+	var buf bytes.Buffer
+	if result.NoIndex {
+		_, _ = fmt.Fprintf(&buf, "Links found on the page %s (noindex)\n", result.Link)
+	} else {
+		_, _ = fmt.Fprintf(&buf, "Links found on the page %s\n", result.Link)
+	}
+	if s.withMetadata && result.Metadata != nil {
+		// Metadata contains only JSON-compatible values
+		metadata, _ := json.Marshal(result.Metadata)
+		_, _ = fmt.Fprintf(&buf, "Metadata: %s\n", metadata)
+	}
+	for i := range result.Links {
+		_, _ = fmt.Fprintf(&buf, "\t%s\n", result.Links[i])
+	}
+	// Single write keeps the page within one file when rotating
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+func (s *TextSink) Close() error {
+	return closeWriter(s.w)
+}
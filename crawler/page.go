@@ -0,0 +1,140 @@
+package crawler
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/fingerprint"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_parser"
+)
+
+// PageProcessor fetches and parses pages, e.g. on remote workers; the crawler processes pages itself unless set
+type PageProcessor interface {
+	Process(job Job) Page
+}
+
+// JobQueue keeps jobs processed elsewhere, e.g. by remote workers, and decides how many are processed at once;
+// jobs are queued without waiting for request slots
+type JobQueue interface {
+	// Queue adds the job: start is called before the job is handed out, the job is dropped if it returns false;
+	// done is called once with the page of a started job. start does not block and may be called holding a lock,
+	// done waits for the crawler to take the page and must not be
+	Queue(job Job, start func() bool, done func(Page))
+	// Pause stops or resumes handing jobs out
+	Pause(paused bool)
+}
+
+// Page is the outcome of fetching and parsing a single page before found links are filtered,
+// exchanged with page processors running elsewhere
+type Page struct {
+	Link          string                  `json:"link"`
+	StatusCode    int                     `json:"status,omitempty"`
+	Redirects     []page_fetcher.Redirect `json:"redirects,omitempty"`
+	FetchDuration time.Duration           `json:"fetch_duration,omitempty"`
+	BodySize      int64                   `json:"body_size,omitempty"`
+	CanonicalLink string                  `json:"canonical_url,omitempty"`
+	// Links resolved against the page URL, in the order of appearance
+	Links     []string           `json:"links,omitempty"`
+	PageLinks []page_parser.Link `json:"page_links,omitempty"`
+	// Links robots directives allow to follow
+	FollowLinks []string                  `json:"follow_links,omitempty"`
+	NoIndex     bool                      `json:"noindex,omitempty"`
+	Metadata    *page_parser.PageMetadata `json:"metadata,omitempty"`
+	Fingerprint *fingerprint.Fingerprint  `json:"fingerprint,omitempty"`
+	Text        string                    `json:"text,omitempty"`
+	Error       *PageError                `json:"error,omitempty"`
+}
+
+// PageError is a page processing error keeping its class when transferred
+type PageError struct {
+	Message string `json:"message"`
+	Class   string `json:"class"`
+}
+
+func (e *PageError) Error() string {
+	return e.Message
+}
+
+// ErrorClass returns the class of the original error, see Result.ErrorClass
+func (e *PageError) ErrorClass() string {
+	return e.Class
+}
+
+// PageProcessor sets processor fetching and parsing pages instead of the crawler
+func (c *Crawler) PageProcessor(p PageProcessor) *Crawler {
+	c.pageProcessor = p
+	return c
+}
+
+// JobQueue sets queue handing jobs out for processing instead of the crawler
+func (c *Crawler) JobQueue(q JobQueue) *Crawler {
+	c.jobQueue = q
+	return c
+}
+
+// Process fetches and parses the page with the crawler fetcher and settings, found links are not filtered
+func (c *Crawler) Process(job Job) Page {
+	return newTask(job, c.taskSettings()).Process(c.fetcher).page()
+}
+
+// page converts result of the page processing into a transferable page
+func (cr crawlResult) page() Page {
+	p := Page{
+		Link:          cr.Link,
+		StatusCode:    cr.StatusCode,
+		Redirects:     cr.Redirects,
+		FetchDuration: cr.FetchDuration,
+		BodySize:      cr.BodySize,
+		CanonicalLink: cr.CanonicalLink,
+		PageLinks:     cr.PageLinks,
+		NoIndex:       cr.NoIndex,
+		Metadata:      cr.Metadata,
+		Fingerprint:   cr.Fingerprint,
+		Text:          cr.Text,
+	}
+	for i := range cr.Links {
+		p.Links = append(p.Links, cr.Links[i].String())
+	}
+	for i := range cr.FollowLinks {
+		p.FollowLinks = append(p.FollowLinks, cr.FollowLinks[i].String())
+	}
+	if cr.Error != nil {
+		p.Error = &PageError{Message: cr.Error.Error(), Class: cr.Result().ErrorClass()}
+	}
+	return p
+}
+
+// crawlResult converts page back into result of the page processing
+func (p Page) crawlResult() crawlResult {
+	result := crawlResult{
+		Link:          p.Link,
+		StatusCode:    p.StatusCode,
+		Redirects:     p.Redirects,
+		FetchDuration: p.FetchDuration,
+		BodySize:      p.BodySize,
+		CanonicalLink: p.CanonicalLink,
+		Links:         parseLinks(p.Links),
+		PageLinks:     p.PageLinks,
+		FollowLinks:   parseLinks(p.FollowLinks),
+		NoIndex:       p.NoIndex,
+		Metadata:      p.Metadata,
+		Fingerprint:   p.Fingerprint,
+		Text:          p.Text,
+	}
+	if p.Error != nil {
+		result.Error = p.Error
+	}
+	return result
+}
+
+// parseLinks parses links, unparseable ones are skipped
+func parseLinks(links []string) []*url.URL {
+	var parsed []*url.URL
+	for _, link := range links {
+		if u, err := url.Parse(link); err == nil {
+			parsed = append(parsed, u)
+		}
+	}
+	return parsed
+}
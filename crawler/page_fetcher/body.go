@@ -0,0 +1,69 @@
+package page_fetcher
+
+import (
+	"io"
+	"time"
+)
+
+// Transfer rate is not checked until the body has been read for this long
+const transferRateGracePeriod = time.Second
+
+// guardedBody enforces body size and transfer rate limits while reading
+type guardedBody struct {
+	body        io.ReadCloser
+	maxSize     int64 // maximum number of bytes to read, 0 for no limit
+	truncate    bool  // whether to stop at maxSize silently instead of failing
+	minRate     int64 // minimum transfer rate in bytes per second, 0 for no limit
+	read        int64 // bytes read so far
+	started     time.Time
+	now         func() time.Time
+	gracePeriod time.Duration
+}
+
+func newGuardedBody(body io.ReadCloser, maxSize int64, truncate bool, minRate int64) *guardedBody {
+	return &guardedBody{
+		body:        body,
+		maxSize:     maxSize,
+		truncate:    truncate,
+		minRate:     minRate,
+		started:     time.Now(),
+		now:         time.Now,
+		gracePeriod: transferRateGracePeriod,
+	}
+}
+
+// Read implements io.Reader
+func (g *guardedBody) Read(p []byte) (int, error) {
+	if g.maxSize > 0 {
+		left := g.maxSize - g.read
+		if left <= 0 {
+			if g.truncate {
+				return 0, io.EOF
+			}
+			// Check if there is anything left to read beyond the limit
+			var probe [1]byte
+			if n, err := g.body.Read(probe[:]); n == 0 {
+				return 0, err
+			}
+			return 0, ErrBodyTooLarge
+		}
+		if int64(len(p)) > left {
+			p = p[:left]
+		}
+	}
+	n, err := g.body.Read(p)
+	g.read += int64(n)
+	if g.minRate > 0 && err == nil {
+		if elapsed := g.now().Sub(g.started); elapsed > g.gracePeriod {
+			if float64(g.read)/elapsed.Seconds() < float64(g.minRate) {
+				return n, ErrTooSlow
+			}
+		}
+	}
+	return n, err
+}
+
+// Close implements io.Closer
+func (g *guardedBody) Close() error {
+	return g.body.Close()
+}
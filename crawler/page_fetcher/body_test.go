@@ -0,0 +1,110 @@
+package page_fetcher
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardedBody_Size(t *testing.T) {
+	testCases := []struct {
+		body     string
+		maxSize  int64
+		truncate bool
+		expected string
+		err      error
+	}{
+		{body: "0123456789", maxSize: 0, expected: "0123456789"},
+		{body: "0123456789", maxSize: 10, expected: "0123456789"},
+		{body: "0123456789", maxSize: 20, expected: "0123456789"},
+		{body: "0123456789", maxSize: 5, truncate: true, expected: "01234"},
+		{body: "0123456789", maxSize: 5, err: ErrBodyTooLarge},
+	}
+	for _, tt := range testCases {
+		g := newGuardedBody(io.NopCloser(strings.NewReader(tt.body)), tt.maxSize, tt.truncate, 0)
+		data, err := io.ReadAll(g)
+		if tt.err != nil {
+			assert.Equal(t, tt.err, err, tt.body)
+		} else if assert.NoError(t, err) {
+			assert.Equal(t, tt.expected, string(data))
+		}
+		assert.NoError(t, g.Close())
+	}
+}
+
+func TestGuardedBody_Rate(t *testing.T) {
+	now := time.Now()
+	g := newGuardedBody(io.NopCloser(bytes.NewReader(make([]byte, 100))), 0, false, 10)
+	g.started = now
+	g.now = func() time.Time { return now }
+	buf := make([]byte, 10)
+	// Within grace period any rate is fine
+	n, err := g.Read(buf)
+	assert.Equal(t, 10, n)
+	assert.NoError(t, err)
+	// 20 bytes in 1.5 seconds is fast enough
+	now = now.Add(time.Second + time.Second/2)
+	_, err = g.Read(buf)
+	assert.NoError(t, err)
+	// 30 bytes in 10 seconds is too slow
+	now = now.Add(time.Second * 9)
+	_, err = g.Read(buf)
+	assert.Equal(t, ErrTooSlow, err)
+}
+
+func TestFetch_ContentLengthTooLarge(t *testing.T) {
+	s := startServer()
+	req := &Request{
+		URL: &url.URL{
+			Scheme: "http",
+			Host:   s.listener.Addr().String(),
+		},
+	}
+	{
+		f := NewFetcher(WithTimeout(time.Second), WithHeadRequests(true), WithMaxBodySize(10, false))
+		_, err := f.Fetch(req)
+		assert.Equal(t, ErrBodyTooLarge, err)
+		assert.Equal(t, []string{"HEAD"}, s.methods())
+	}
+	{
+		f := NewFetcher(WithTimeout(time.Second), WithMaxBodySize(10, false))
+		_, err := f.Fetch(req)
+		assert.Equal(t, ErrBodyTooLarge, err)
+		assert.Equal(t, []string{"GET"}, s.methods())
+	}
+	{
+		f := NewFetcher(WithTimeout(time.Second), WithMaxBodySize(10, true), WithMinTransferRate(1))
+		if resp, err := f.Fetch(req); assert.NoError(t, err) {
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, testHTML[:10], string(body))
+			_ = resp.Body.Close()
+		}
+	}
+	_ = s.listener.Close()
+}
+
+func TestFetch_StreamTooLarge(t *testing.T) {
+	s := startServer()
+	s.chunked = true
+	req := &Request{
+		URL: &url.URL{
+			Scheme: "http",
+			Host:   s.listener.Addr().String(),
+		},
+	}
+	f := NewFetcher(WithTimeout(time.Second), WithMaxBodySize(10, false))
+	if resp, err := f.Fetch(req); assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		_, err := io.ReadAll(resp.Body)
+		assert.Equal(t, ErrBodyTooLarge, err)
+		_ = resp.Body.Close()
+	}
+	_ = s.listener.Close()
+}
@@ -4,4 +4,6 @@ import "errors"
 
 var (
 	ErrBadContentType = errors.New("unacceptable content type")
+	ErrBodyTooLarge   = errors.New("response body too large")
+	ErrTooSlow        = errors.New("response transfer rate too low")
 )
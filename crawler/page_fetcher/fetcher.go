@@ -1,7 +1,7 @@
 package page_fetcher
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"time"
@@ -13,7 +13,12 @@ type Fetcher struct {
 	accept         string        // default 'Accept' http header
 	userAgent      string        // default 'User-Agent' http header
 	doHeadRequests bool          // whether to perform HEAD requests before GET requests
-	client         *http.Client  // http client to use for requests
+	headerRules    []HeaderRule  // extra headers to send with matching requests
+	maxBodySize    int64         // maximum response body size in bytes, 0 for no limit
+	truncateBody   bool          // whether to truncate oversized bodies instead of failing
+	minRate        int64         // minimum transfer rate in bytes per second, 0 for no limit
+	logger         *slog.Logger
+	client         *http.Client // http client to use for requests
 }
 
 type method string
@@ -29,7 +34,7 @@ const (
 
 // NewFetcher creates an instance of Fetcher with options
 func NewFetcher(options ...Option) *Fetcher {
-	f := Fetcher{}
+	f := Fetcher{logger: slog.Default()}
 	// apply options
 	for _, fn := range options {
 		fn(&f)
@@ -52,30 +57,68 @@ func NewFetcher(options ...Option) *Fetcher {
 	}
 	// cookiejar.New() without options does not return an error
 	f.client.Jar, _ = cookiejar.New(nil)
+	for i := range f.headerRules {
+		f.logger.Debug("Header rule", "index", i+1, "rule", f.headerRules[i].String())
+	}
 	return &f
 }
 
 // Fetch performs http requests and build response object
 func (f *Fetcher) Fetch(r *Request) (*Response, error) {
 	if f.doHeadRequests {
-		resp, err := f.client.Do(f.buildRequest(r, methodHEAD))
-		if err != nil {
+		if _, err := f.Probe(r); err == ErrBadContentType || err == ErrBodyTooLarge {
+			return nil, err
+		} else if err != nil {
 			// Error on HEAD request is not critical, let's do GET anyway
-			log.Printf("HEAD request error: %s", err)
-		} else if !r.acceptableResponse(resp) {
-			return nil, ErrBadContentType
+			f.logger.Warn("HEAD request failed", "url", r.URL.String(), "host", r.URL.Host, "error", err)
 		}
 	}
+	start := time.Now()
 	resp, err := f.client.Do(f.buildRequest(r, methodGET))
 	if err != nil {
 		return nil, err
 	}
+	f.logger.Debug("Response received", "url", r.URL.String(), "host", r.URL.Host, "status", resp.StatusCode,
+		"duration", time.Since(start))
 	if !r.acceptableResponse(resp) {
+		_ = resp.Body.Close()
 		return nil, ErrBadContentType
 	}
+	if f.tooLarge(resp) {
+		_ = resp.Body.Close()
+		return nil, ErrBodyTooLarge
+	}
+	if f.maxBodySize > 0 || f.minRate > 0 {
+		resp.Body = newGuardedBody(resp.Body, f.maxBodySize, f.truncateBody, f.minRate)
+	}
 	return buildResponse(r, resp), nil
 }
 
+// Probe performs HEAD request only, the response has empty body;
+// responses failing content type or size checks are returned along with ErrBadContentType or ErrBodyTooLarge
+func (f *Fetcher) Probe(r *Request) (*Response, error) {
+	resp, err := f.client.Do(f.buildRequest(r, methodHEAD))
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	resp.Body = http.NoBody
+	response := buildResponse(r, resp)
+	if !r.acceptableResponse(resp) {
+		return response, ErrBadContentType
+	}
+	if f.tooLarge(resp) {
+		return response, ErrBodyTooLarge
+	}
+	return response, nil
+}
+
+// tooLarge tells if the response declares body size over the limit,
+// bodies to be truncated are never too large
+func (f *Fetcher) tooLarge(resp *http.Response) bool {
+	return f.maxBodySize > 0 && !f.truncateBody && resp.ContentLength > f.maxBodySize
+}
+
 // buildRequest assembles http.Request according to parameters
 func (f Fetcher) buildRequest(r *Request, method method) *http.Request {
 	link := r.URL.String()
@@ -86,6 +129,10 @@ func (f Fetcher) buildRequest(r *Request, method method) *http.Request {
 	}
 	httpRequest.Header.Add("Referer", r.HTTPReferrer)
 	httpRequest.Header.Add("Accept", f.accept)
+	applied := applyHeaderRules(f.headerRules, httpRequest)
+	if len(applied) > 0 {
+		f.logger.Debug("Applied header rules", "method", string(method), "url", link, "rules", applied)
+	}
 	return httpRequest
 }
 
@@ -95,5 +142,6 @@ func buildResponse(req *Request, resp *http.Response) *Response {
 		StatusCode: resp.StatusCode,
 		Headers:    resp.Header,
 		Body:       resp.Body,
+		Redirects:  redirectChain(resp),
 	}
 }
@@ -4,7 +4,9 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"testing"
 	"time"
 
@@ -176,16 +178,68 @@ func TestContentTypeGet(t *testing.T) {
 	_ = s.listener.Close()
 }
 
+func TestProbe(t *testing.T) {
+	s := startServer()
+	req := &Request{
+		URL: &url.URL{
+			Scheme: "http",
+			Host:   s.listener.Addr().String(),
+		},
+		AcceptableContentTypes: map[string]struct{}{"text/html": {}},
+	}
+	f := NewFetcher(WithUserAgent("Bot/1"))
+	if resp, err := f.Probe(req); assert.NoError(t, err) {
+		assert.Equal(t, s.responseCode, resp.StatusCode)
+		assert.Equal(t, "text/html", resp.Headers.Get("Content-Type"))
+		assert.Equal(t, []string{"HEAD"}, s.methods())
+		assert.Equal(t, []string{"Bot/1"}, s.userAgents())
+	}
+	req.AcceptableContentTypes = map[string]struct{}{"application/binary": {}}
+	resp, err := f.Probe(req)
+	assert.Equal(t, ErrBadContentType, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, "text/html", resp.Headers.Get("Content-Type"))
+	}
+	_ = s.listener.Close()
+}
+
+func TestFetch_Redirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/moved", http.StatusMovedPermanently))
+	mux.Handle("/moved", http.RedirectHandler("/new", http.StatusFound))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(testHTML))
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	f := NewFetcher()
+	u, _ := url.Parse(s.URL + "/old")
+	if resp, err := f.Fetch(&Request{URL: u, AcceptableContentTypes: map[string]struct{}{"text/html": {}}}); assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, []Redirect{
+			{From: s.URL + "/old", To: s.URL + "/moved", StatusCode: http.StatusMovedPermanently},
+			{From: s.URL + "/moved", To: s.URL + "/new", StatusCode: http.StatusFound},
+		}, resp.Redirects)
+	}
+	u, _ = url.Parse(s.URL + "/new")
+	if resp, err := f.Fetch(&Request{URL: u}); assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Empty(t, resp.Redirects)
+	}
+}
 
 type testServer struct {
 	listener        net.Listener
 	contentType     string
 	responseCode    int
 	failHeadRequest bool
+	chunked         bool
 	seenMethods     []string
 	seenUAs         []string
 	seenReferrers   []string
 	seenAccept      []string
+	seenLanguages   []string
 }
 
 func (t *testServer) methods() []string {
@@ -216,11 +270,19 @@ func (t *testServer) accepts() []string {
 	return m
 }
 
+func (t *testServer) languages() []string {
+	m := make([]string, len(t.seenLanguages))
+	copy(m, t.seenLanguages)
+	t.seenLanguages = t.seenLanguages[0:0]
+	return m
+}
+
 func (t *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	t.seenMethods = append(t.seenMethods, r.Method)
 	t.seenUAs = append(t.seenUAs, r.Header.Get("User-Agent"))
 	t.seenReferrers = append(t.seenReferrers, r.Header.Get("Referer"))
 	t.seenAccept = append(t.seenAccept, r.Header.Get("Accept"))
+	t.seenLanguages = append(t.seenLanguages, r.Header.Get("Accept-Language"))
 	if t.failHeadRequest && r.Method == "HEAD" {
 		hj, _ := w.(http.Hijacker)
 		conn, _, _ := hj.Hijack()
@@ -232,7 +294,14 @@ func (t *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Add("Content-Type", t.contentType)
+	if t.contentType == "text/html" && !t.chunked {
+		w.Header().Add("Content-Length", strconv.Itoa(len(testHTML)))
+	}
 	w.WriteHeader(t.responseCode)
+	if t.chunked {
+		// Flushing before writing the body prevents setting Content-Length
+		w.(http.Flusher).Flush()
+	}
 	if t.contentType == "text/css" {
 		if _, err := w.Write([]byte(testCSS)); err != nil {
 			panic(err)
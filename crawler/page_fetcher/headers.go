@@ -0,0 +1,67 @@
+package page_fetcher
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// HeaderRule describes a set of extra headers to send with requests matching the rule.
+// A rule without Host and URLPattern applies to every request.
+type HeaderRule struct {
+	// Host the rule is limited to, empty for any host
+	Host string
+	// URL regular expression the rule is limited to, nil for any URL
+	URLPattern *regexp.Regexp
+	// Headers to set on matching requests
+	Headers http.Header
+}
+
+// matches tells if the rule should be applied to the URL
+func (h HeaderRule) matches(u *url.URL) bool {
+	if h.Host != "" && !strings.EqualFold(h.Host, u.Hostname()) {
+		return false
+	}
+	if h.URLPattern != nil && !h.URLPattern.MatchString(u.String()) {
+		return false
+	}
+	return true
+}
+
+// String returns human-readable rule representation, used in logs
+func (h HeaderRule) String() string {
+	scope := "*"
+	if h.Host != "" {
+		scope = "host=" + h.Host
+	}
+	if h.URLPattern != nil {
+		scope += " url~" + h.URLPattern.String()
+	}
+	names := make([]string, 0, len(h.Headers))
+	for name := range h.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return scope + " [" + strings.Join(names, ", ") + "]"
+}
+
+// applyHeaderRules merges headers of all matching rules into the request,
+// later rules override values set by earlier ones
+func applyHeaderRules(rules []HeaderRule, req *http.Request) []HeaderRule {
+	var applied []HeaderRule
+	for i := range rules {
+		if !rules[i].matches(req.URL) {
+			continue
+		}
+		for name, values := range rules[i].Headers {
+			req.Header.Del(name)
+			for _, value := range values {
+				req.Header.Add(name, value)
+			}
+		}
+		applied = append(applied, rules[i])
+	}
+	return applied
+}
@@ -0,0 +1,106 @@
+package page_fetcher
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyHeaderRules(t *testing.T) {
+	rules := []HeaderRule{
+		{
+			Headers: http.Header{"Accept-Language": {"en-US"}},
+		},
+		{
+			Host:    "staging.example.com",
+			Headers: http.Header{"X-Forwarded-For": {"10.0.0.1"}},
+		},
+		{
+			URLPattern: regexp.MustCompile(`/beta/`),
+			Headers: http.Header{
+				"X-Feature":       {"on"},
+				"Accept-Language": {"de-DE"},
+			},
+		},
+	}
+	testCases := []struct {
+		link     string
+		expected http.Header
+		applied  int
+	}{
+		{
+			link:     "http://example.com/",
+			expected: http.Header{"Accept-Language": {"en-US"}},
+			applied:  1,
+		},
+		{
+			link: "http://Staging.Example.com/",
+			expected: http.Header{
+				"Accept-Language": {"en-US"},
+				"X-Forwarded-For": {"10.0.0.1"},
+			},
+			applied: 2,
+		},
+		{
+			link: "http://example.com/beta/page",
+			expected: http.Header{
+				"Accept-Language": {"de-DE"},
+				"X-Feature":       {"on"},
+			},
+			applied: 2,
+		},
+	}
+	for _, tt := range testCases {
+		req, _ := http.NewRequest(http.MethodGet, tt.link, nil)
+		applied := applyHeaderRules(rules, req)
+		assert.Len(t, applied, tt.applied, tt.link)
+		assert.Equal(t, tt.expected, req.Header, tt.link)
+	}
+}
+
+func TestHeaderRuleString(t *testing.T) {
+	assert.Equal(t, "* [A, B]", HeaderRule{Headers: http.Header{"B": nil, "A": nil}}.String())
+	assert.Equal(t, "host=example.com url~^/x [A]", HeaderRule{
+		Host:       "example.com",
+		URLPattern: regexp.MustCompile(`^/x`),
+		Headers:    http.Header{"A": nil},
+	}.String())
+}
+
+func TestFetch_HeaderRules(t *testing.T) {
+	s := startServer()
+	var logs bytes.Buffer
+	f := NewFetcher(
+		WithTimeout(time.Second),
+		WithUserAgent("Bot/1"),
+		WithHeadRequests(true),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		WithHeaderRules(
+			HeaderRule{Headers: http.Header{"Accept-Language": {"uk"}}},
+			HeaderRule{Host: "other.host", Headers: http.Header{"Accept-Language": {"en"}}},
+			HeaderRule{Headers: http.Header{"User-Agent": {"Bot/2"}}},
+		),
+	)
+	r := &Request{
+		URL: &url.URL{
+			Scheme: "http",
+			Host:   s.listener.Addr().String(),
+		},
+	}
+	if resp, err := f.Fetch(r); assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, []string{"HEAD", "GET"}, s.methods())
+		assert.Equal(t, []string{"uk", "uk"}, s.languages())
+		assert.Equal(t, []string{"Bot/2", "Bot/2"}, s.userAgents())
+		assert.Contains(t, logs.String(), `msg="Header rule" index=3`)
+		assert.Contains(t, logs.String(), `msg="Applied header rules" method=GET`)
+		assert.Contains(t, logs.String(), `msg="Response received"`)
+	}
+	_ = s.listener.Close()
+}
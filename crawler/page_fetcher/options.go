@@ -1,6 +1,9 @@
 package page_fetcher
 
-import "time"
+import (
+	"log/slog"
+	"time"
+)
 
 type Option func(f *Fetcher)
 
@@ -31,3 +34,32 @@ func WithHeadRequests(doHeadRequests bool) Option {
 		f.doHeadRequests = doHeadRequests
 	}
 }
+
+// WithHeaderRules adds rules for extra request headers, rules are applied in order
+func WithHeaderRules(rules ...HeaderRule) Option {
+	return func(f *Fetcher) {
+		f.headerRules = append(f.headerRules, rules...)
+	}
+}
+
+// WithLogger sets logger, header rules and responses are logged at debug level
+func WithLogger(logger *slog.Logger) Option {
+	return func(f *Fetcher) {
+		f.logger = logger
+	}
+}
+
+// WithMaxBodySize limits response body size, oversized bodies are either truncated or rejected with ErrBodyTooLarge
+func WithMaxBodySize(maxBytes int64, truncate bool) Option {
+	return func(f *Fetcher) {
+		f.maxBodySize, f.truncateBody = maxBytes, truncate
+	}
+}
+
+// WithMinTransferRate sets the minimum body transfer rate in bytes per second,
+// slower responses fail with ErrTooSlow
+func WithMinTransferRate(bytesPerSecond int64) Option {
+	return func(f *Fetcher) {
+		f.minRate = bytesPerSecond
+	}
+}
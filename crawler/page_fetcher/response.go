@@ -16,4 +16,33 @@ type Response struct {
 	Headers http.Header
 	// Page contents
 	Body io.ReadCloser
+	// Redirects followed to get the response, in order
+	Redirects []Redirect
+}
+
+// Redirect is a single redirect hop
+type Redirect struct {
+	// Redirecting URL
+	From string `json:"from"`
+	// Redirect target
+	To string `json:"to"`
+	// Redirect response status code
+	StatusCode int `json:"status"`
+}
+
+// redirectChain lists redirects that led to the response
+func redirectChain(resp *http.Response) []Redirect {
+	var chain []Redirect
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append(chain, Redirect{
+			From:       req.Response.Request.URL.String(),
+			To:         req.URL.String(),
+			StatusCode: req.Response.StatusCode,
+		})
+	}
+	// The chain is collected backwards
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
 }
@@ -0,0 +1,24 @@
+package page_parser
+
+import (
+	"bufio"
+	"io"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Number of bytes to inspect when looking for BOM and <meta charset>
+const charsetPrescanSize = 1024
+
+// toUTF8 determines character set of the content using BOM, content type and <meta> tags
+// and returns a reader transcoding the content into UTF-8 along with the character set name
+func toUTF8(body io.Reader, contentType string) (io.Reader, string) {
+	r := bufio.NewReaderSize(body, charsetPrescanSize)
+	// Peek returns an error if there is less data than requested, we are fine with that
+	head, _ := r.Peek(charsetPrescanSize)
+	enc, name, _ := charset.DetermineEncoding(head, contentType)
+	if name == "utf-8" {
+		return r, name
+	}
+	return enc.NewDecoder().Reader(r), name
+}
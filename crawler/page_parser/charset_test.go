@@ -0,0 +1,68 @@
+package page_parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestParse_Charset(t *testing.T) {
+	testCases := []struct {
+		name        string
+		html        string
+		encoding    encoding.Encoding
+		contentType string
+		charset     string
+	}{
+		{
+			name:        "content type header",
+			html:        `<html><body><a href="/статья">Статья</a></body></html>`,
+			encoding:    charmap.Windows1251,
+			contentType: "text/html; charset=windows-1251",
+			charset:     "windows-1251",
+		},
+		{
+			name:     "meta charset",
+			html:     `<html><head><meta charset="shift_jis"></head><body><a href="/記事">記事</a></body></html>`,
+			encoding: japanese.ShiftJIS,
+			charset:  "shift_jis",
+		},
+		{
+			name:     "meta http-equiv",
+			html:     `<html><head><meta http-equiv="Content-Type" content="text/html; charset=ISO-8859-1"></head><body><a href="/café">café</a></body></html>`,
+			encoding: charmap.ISO8859_1,
+			// WHATWG encoding spec maps ISO-8859-1 to windows-1252
+			charset: "windows-1252",
+		},
+		{
+			name:     "byte order mark",
+			html:     `<html><body><a href="/straße">Straße</a></body></html>`,
+			encoding: unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+			charset:  "utf-16le",
+		},
+		{
+			name:     "plain utf-8",
+			html:     `<html><body><a href="/straße">Straße</a></body></html>`,
+			encoding: encoding.Nop,
+			charset:  "utf-8",
+		},
+	}
+	for _, tt := range testCases {
+		encoded, err := tt.encoding.NewEncoder().Bytes([]byte(tt.html))
+		if !assert.NoError(t, err, tt.name) {
+			continue
+		}
+		page, err := Parse(bytes.NewReader(encoded), WithContentType(tt.contentType))
+		if assert.NoError(t, err, tt.name) {
+			assert.Equal(t, tt.charset, page.Charset, tt.name)
+			expected := []string{"/статья", "/記事", "/café", "/straße"}
+			assert.Len(t, page.Links, 1, tt.name)
+			assert.Contains(t, expected, page.Links[0].URL, tt.name)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package page_parser
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// LinkSource identifies the element and attribute a link was found in
+type LinkSource string
+
+const (
+	SourceAnchor      LinkSource = "a[href]"
+	SourceArea        LinkSource = "area[href]"
+	SourceIFrame      LinkSource = "iframe[src]"
+	SourceFrame       LinkSource = "frame[src]"
+	SourceLink        LinkSource = "link[href]" // only rel=next, prev and alternate
+	SourceForm        LinkSource = "form[action]"
+	SourceMetaRefresh LinkSource = "meta[http-equiv=refresh]"
+)
+
+// DefaultLinkSources lists link sources used unless set with WithLinkSources
+var DefaultLinkSources = []LinkSource{SourceAnchor}
+
+// AllLinkSources lists all supported link sources in the order of preference
+var AllLinkSources = []LinkSource{
+	SourceAnchor,
+	SourceArea,
+	SourceIFrame,
+	SourceFrame,
+	SourceLink,
+	SourceForm,
+	SourceMetaRefresh,
+}
+
+// LinkPosition tells which part of the page a link is located in
+type LinkPosition string
+
+const (
+	PositionHead   LinkPosition = "head"
+	PositionNav    LinkPosition = "nav"
+	PositionHeader LinkPosition = "header"
+	PositionFooter LinkPosition = "footer"
+	PositionBody   LinkPosition = "body"
+)
+
+// Landmark elements and ARIA roles defining link position
+var (
+	landmarkElements = map[string]LinkPosition{
+		"head":   PositionHead,
+		"nav":    PositionNav,
+		"header": PositionHeader,
+		"footer": PositionFooter,
+	}
+	landmarkRoles = map[string]LinkPosition{
+		"navigation":  PositionNav,
+		"banner":      PositionHeader,
+		"contentinfo": PositionFooter,
+	}
+)
+
+// Link is a link found on the page
+type Link struct {
+	// Link URL, resolved against <base href> if present
+	URL string `json:"url"`
+	// Element and attribute the link came from
+	Source LinkSource `json:"source"`
+	// Anchor text with collapsed whitespace, 'alt' for <area>
+	Text string `json:"text,omitempty"`
+	// Lowercased values of 'rel' attribute
+	Rel []string `json:"rel,omitempty"`
+	// Value of 'title' attribute
+	Title string `json:"title,omitempty"`
+	// Value of 'target' attribute
+	Target string `json:"target,omitempty"`
+	// Value of 'hreflang' attribute
+	HrefLang string `json:"hreflang,omitempty"`
+	// Page part determined by the nearest landmark element
+	Position LinkPosition `json:"position"`
+}
+
+// NoFollow tells if the link is marked with rel="nofollow"
+func (l Link) NoFollow() bool {
+	for _, rel := range l.Rel {
+		if rel == "nofollow" {
+			return true
+		}
+	}
+	return false
+}
+
+// linkRel returns lowercased values of element 'rel' attribute
+func linkRel(s *goquery.Selection) []string {
+	if rel := strings.Fields(strings.ToLower(s.AttrOr("rel", ""))); len(rel) > 0 {
+		return rel
+	}
+	return nil
+}
+
+// Navigational <link> relations worth following
+var followableLinkRels = map[string]struct{}{
+	"next":      {},
+	"prev":      {},
+	"alternate": {},
+}
+
+// linkSelector matches all elements that may carry links
+const linkSelector = `a[href], area[href], iframe[src], frame[src], link[href], form[action], meta[http-equiv][content]`
+
+// extractLink returns the link carried by the element along with its attributes, if any
+func extractLink(s *goquery.Selection) (Link, bool) {
+	href, source, ok := extractHref(s)
+	if !ok {
+		return Link{}, false
+	}
+	link := Link{
+		URL:      href,
+		Source:   source,
+		Rel:      linkRel(s),
+		Title:    strings.TrimSpace(s.AttrOr("title", "")),
+		Target:   strings.TrimSpace(s.AttrOr("target", "")),
+		HrefLang: strings.TrimSpace(s.AttrOr("hreflang", "")),
+		Position: linkPosition(s),
+	}
+	switch source {
+	case SourceAnchor:
+		link.Text = collapseSpaces(s.Text())
+	case SourceArea:
+		link.Text = strings.TrimSpace(s.AttrOr("alt", ""))
+	}
+	return link, true
+}
+
+// linkPosition finds the nearest landmark among element ancestors
+func linkPosition(s *goquery.Selection) LinkPosition {
+	for node := s.Get(0).Parent; node != nil; node = node.Parent {
+		if position, ok := landmarkElements[node.Data]; ok {
+			return position
+		}
+		for _, attr := range node.Attr {
+			if attr.Key == "role" {
+				if position, ok := landmarkRoles[strings.ToLower(strings.TrimSpace(attr.Val))]; ok {
+					return position
+				}
+			}
+		}
+	}
+	return PositionBody
+}
+
+// extractHref returns the link carried by the element and its source, if any
+func extractHref(s *goquery.Selection) (string, LinkSource, bool) {
+	switch goquery.NodeName(s) {
+	case "a":
+		href, ok := s.Attr("href")
+		return href, SourceAnchor, ok
+	case "area":
+		href, ok := s.Attr("href")
+		return href, SourceArea, ok
+	case "iframe":
+		src, ok := s.Attr("src")
+		return src, SourceIFrame, ok
+	case "frame":
+		src, ok := s.Attr("src")
+		return src, SourceFrame, ok
+	case "link":
+		for _, rel := range linkRel(s) {
+			if _, ok := followableLinkRels[rel]; ok {
+				href, ok := s.Attr("href")
+				return href, SourceLink, ok
+			}
+		}
+	case "form":
+		if method := strings.TrimSpace(s.AttrOr("method", "get")); strings.EqualFold(method, "get") {
+			action, ok := s.Attr("action")
+			return action, SourceForm, ok
+		}
+	case "meta":
+		if strings.EqualFold(strings.TrimSpace(s.AttrOr("http-equiv", "")), "refresh") {
+			return refreshURL(s.AttrOr("content", ""))
+		}
+	}
+	return "", "", false
+}
+
+// refreshURL extracts URL from <meta http-equiv="refresh" content="5; url=/page"> content value
+func refreshURL(content string) (string, LinkSource, bool) {
+	parts := strings.SplitN(content, ";", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	value := strings.TrimSpace(parts[1])
+	if len(value) > 3 && strings.EqualFold(value[:3], "url") {
+		value = strings.TrimSpace(value[3:])
+		if !strings.HasPrefix(value, "=") {
+			return "", "", false
+		}
+		value = strings.TrimSpace(value[1:])
+	}
+	value = strings.Trim(value, `'"`)
+	return value, SourceMetaRefresh, value != ""
+}
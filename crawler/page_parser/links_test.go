@@ -0,0 +1,131 @@
+package page_parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_LinkSources(t *testing.T) {
+	r := bytes.NewReader([]byte(html3))
+	if result, err := Parse(r, WithLinkSources(AllLinkSources...)); assert.NoError(t, err) {
+		assert.Equal(t, []Link{
+			{URL: "http://example.com/refresh", Source: SourceMetaRefresh, Position: PositionHead},
+			{URL: "http://example.com/page/2", Source: SourceLink, Rel: []string{"next"}, Position: PositionHead},
+			{URL: "http://example.com/de/", Source: SourceLink, Rel: []string{"alternate"}, HrefLang: "de", Position: PositionHead},
+			{URL: "http://example.com/anchor", Source: SourceAnchor, Text: "Anchor", Position: PositionBody},
+			{URL: "http://example.com/sponsored", Source: SourceAnchor, Text: "Sponsored", Rel: []string{"sponsored", "nofollow"}, Position: PositionBody},
+			{URL: "http://example.com/map", Source: SourceArea, Text: "Map", Position: PositionBody},
+			{URL: "http://example.com/frame", Source: SourceIFrame, Position: PositionBody},
+			{URL: "http://example.com/search", Source: SourceForm, Position: PositionBody},
+			{URL: "http://example.com/find", Source: SourceForm, Position: PositionBody},
+		}, result.Links)
+	}
+}
+
+func TestParse_DefaultLinkSources(t *testing.T) {
+	r := bytes.NewReader([]byte(html3))
+	if result, err := Parse(r); assert.NoError(t, err) {
+		assert.Equal(t, []Link{
+			{URL: "http://example.com/anchor", Source: SourceAnchor, Text: "Anchor", Position: PositionBody},
+			{URL: "http://example.com/sponsored", Source: SourceAnchor, Text: "Sponsored", Rel: []string{"sponsored", "nofollow"}, Position: PositionBody},
+		}, result.Links)
+	}
+}
+
+func TestParse_Frameset(t *testing.T) {
+	r := bytes.NewReader([]byte(`<html><frameset><frame src="/a"><frame src="/b"></frameset></html>`))
+	if result, err := Parse(r, WithLinkSources(SourceFrame)); assert.NoError(t, err) {
+		assert.Equal(t, []Link{
+			{URL: "/a", Source: SourceFrame, Position: PositionBody},
+			{URL: "/b", Source: SourceFrame, Position: PositionBody},
+		}, result.Links)
+	}
+}
+
+func TestParse_WithLinkSources(t *testing.T) {
+	r := bytes.NewReader([]byte(html3))
+	if result, err := Parse(r, WithLinkSources(SourceAnchor, SourceArea)); assert.NoError(t, err) {
+		assert.Equal(t, []string{
+			"http://example.com/anchor",
+			"http://example.com/sponsored",
+			"http://example.com/map",
+		}, result.URLs())
+	}
+}
+
+func TestParse_LinkAttributes(t *testing.T) {
+	r := bytes.NewReader([]byte(html5))
+	if result, err := Parse(r); assert.NoError(t, err) {
+		assert.Equal(t, []Link{
+			{URL: "/logo", Source: SourceAnchor, Text: "Logo", Position: PositionHeader},
+			{URL: "/menu", Source: SourceAnchor, Text: "Menu item", Title: "Go to menu", Position: PositionNav},
+			{URL: "/aria-menu", Source: SourceAnchor, Text: "ARIA menu", Position: PositionNav},
+			{URL: "/article", Source: SourceAnchor, Text: "Read the article", Target: "_blank", HrefLang: "en", Position: PositionBody},
+			{URL: "/about", Source: SourceAnchor, Text: "About", Position: PositionFooter},
+			{URL: "/terms", Source: SourceAnchor, Text: "Terms", Position: PositionFooter},
+		}, result.Links)
+	}
+}
+
+func TestRefreshURL(t *testing.T) {
+	testCases := []struct {
+		content string
+		link    string
+		ok      bool
+	}{
+		{content: "5", ok: false},
+		{content: "0; url=/a", link: "/a", ok: true},
+		{content: "0;URL='/b'", link: "/b", ok: true},
+		{content: `0; Url = "/c"`, link: "/c", ok: true},
+		{content: "0; /d", link: "/d", ok: true},
+		{content: "0; url/e", ok: false},
+		{content: "0; ", ok: false},
+	}
+	for _, tt := range testCases {
+		link, _, ok := refreshURL(tt.content)
+		if assert.Equal(t, tt.ok, ok, tt.content) {
+			assert.Equal(t, tt.link, link, tt.content)
+		}
+	}
+}
+
+// language=HTML
+const html3 = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<base href="http://example.com/">
+	<meta http-equiv="Refresh" content="10; url=/refresh">
+	<meta http-equiv="Content-Type" content="text/html; charset=utf-8">
+	<link rel="stylesheet" href="/style.css">
+	<link rel="next" href="/page/2">
+	<link rel="alternate" hreflang="de" href="/de/">
+	<title>Foo Bar</title>
+</head>
+<body>
+	<a href="/anchor">Anchor</a>
+	<a href="/sponsored" rel="Sponsored  NoFollow">Sponsored</a>
+	<map name="m"><area shape="rect" coords="0,0,1,1" href="/map" alt="Map"></map>
+	<iframe src="/frame"></iframe>
+	<form action="/search"><input name="q"></form>
+	<form action="/find" method="GET"><input name="q"></form>
+	<form action="/login" method="post"><input name="user"></form>
+</body>
+</html>
+`
+
+// language=HTML
+const html5 = `<!DOCTYPE html>
+<html lang="en">
+<body>
+	<header><a href="/logo">Logo</a></header>
+	<nav><ul><li><a href="/menu" title="Go to menu">Menu
+		item</a></li></ul></nav>
+	<div role="navigation"><a href="/aria-menu">ARIA menu</a></div>
+	<main><p><a href="/article" target="_blank" hreflang="en">Read <b>the</b> article</a></p></main>
+	<footer><a href="/about">About</a></footer>
+	<div role="contentinfo"><a href="/terms">Terms</a></div>
+</body>
+</html>
+`
@@ -0,0 +1,111 @@
+package page_parser
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// PageMetadata contains descriptive page data used for content audits
+type PageMetadata struct {
+	// <title> text
+	Title string `json:"title,omitempty"`
+	// <meta name="description"> content
+	Description string `json:"description,omitempty"`
+	// <meta name="robots"> content as is
+	Robots string `json:"robots,omitempty"`
+	// <html lang="..."> value
+	Lang string `json:"lang,omitempty"`
+	// Outline of h1-h3 headings in document order
+	Headings []Heading `json:"headings,omitempty"`
+	// Language alternates: <link rel="alternate" hreflang="..." href="...">
+	Alternates []Alternate `json:"alternates,omitempty"`
+	// OpenGraph properties: <meta property="og:..." content="...">, first value wins
+	OpenGraph map[string]string `json:"open_graph,omitempty"`
+	// Twitter card properties: <meta name="twitter:..." content="...">, first value wins
+	TwitterCard map[string]string `json:"twitter_card,omitempty"`
+	// Parsed <script type="application/ld+json"> blocks, invalid blocks are skipped
+	JSONLD []interface{} `json:"json_ld,omitempty"`
+}
+
+// Heading is a single h1-h3 heading
+type Heading struct {
+	// Heading level, 1 to 3
+	Level int `json:"level,omitempty"`
+	// Heading text with collapsed whitespace
+	Text string `json:"text,omitempty"`
+}
+
+// Alternate is a language alternate of the page
+type Alternate struct {
+	// Language code, e.g. "en-GB" or "x-default"
+	HrefLang string `json:"hreflang,omitempty"`
+	// Alternate page URL, resolved against <base href> if present
+	URL string `json:"url,omitempty"`
+}
+
+// extractMetadata collects page metadata from the document
+func extractMetadata(doc *goquery.Document) PageMetadata {
+	m := PageMetadata{
+		Title: collapseSpaces(doc.Find("title").First().Text()),
+		Lang:  strings.TrimSpace(doc.Find("html").First().AttrOr("lang", "")),
+	}
+	doc.Find(`meta[content]`).Each(func(i int, s *goquery.Selection) {
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		name := strings.ToLower(strings.TrimSpace(s.AttrOr("name", "")))
+		property := strings.ToLower(strings.TrimSpace(s.AttrOr("property", "")))
+		switch {
+		case name == "description" && m.Description == "":
+			m.Description = content
+		case name == "robots" && m.Robots == "":
+			m.Robots = content
+		case strings.HasPrefix(property, "og:"):
+			m.OpenGraph = setOnce(m.OpenGraph, property, content)
+		case strings.HasPrefix(name, "twitter:"):
+			m.TwitterCard = setOnce(m.TwitterCard, name, content)
+		case strings.HasPrefix(property, "twitter:"):
+			m.TwitterCard = setOnce(m.TwitterCard, property, content)
+		}
+	})
+	doc.Find("h1, h2, h3").Each(func(i int, s *goquery.Selection) {
+		m.Headings = append(m.Headings, Heading{
+			Level: int(goquery.NodeName(s)[1] - '0'),
+			Text:  collapseSpaces(s.Text()),
+		})
+	})
+	doc.Find(`link[rel][hreflang][href]`).Each(func(i int, s *goquery.Selection) {
+		for _, rel := range linkRel(s) {
+			if rel == "alternate" {
+				m.Alternates = append(m.Alternates, Alternate{
+					HrefLang: strings.TrimSpace(s.AttrOr("hreflang", "")),
+					URL:      strings.TrimSpace(s.AttrOr("href", "")),
+				})
+				break
+			}
+		}
+	})
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var block interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &block); err == nil {
+			m.JSONLD = append(m.JSONLD, block)
+		}
+	})
+	return m
+}
+
+// setOnce sets the key unless it is already set, initializing the map if needed
+func setOnce(m map[string]string, key, value string) map[string]string {
+	if m == nil {
+		m = make(map[string]string)
+	}
+	if _, ok := m[key]; !ok {
+		m[key] = value
+	}
+	return m
+}
+
+// collapseSpaces trims the text and replaces whitespace sequences with single spaces
+func collapseSpaces(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
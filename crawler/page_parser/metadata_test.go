@@ -0,0 +1,81 @@
+package page_parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Metadata(t *testing.T) {
+	r := bytes.NewReader([]byte(html4))
+	if result, err := Parse(r); assert.NoError(t, err) {
+		assert.Equal(t, PageMetadata{
+			Title:       "Foo Bar",
+			Description: "All about foo",
+			Robots:      "index, follow",
+			Lang:        "en-GB",
+			Headings: []Heading{
+				{Level: 1, Text: "Foo"},
+				{Level: 2, Text: "Bar baz"},
+				{Level: 3, Text: "Qux"},
+			},
+			Alternates: []Alternate{
+				{HrefLang: "de", URL: "http://example.com/de/foo"},
+				{HrefLang: "x-default", URL: "http://example.com/foo"},
+			},
+			OpenGraph: map[string]string{
+				"og:title": "Foo",
+				"og:image": "http://example.com/1.png",
+			},
+			TwitterCard: map[string]string{
+				"twitter:card":  "summary",
+				"twitter:title": "Foo",
+			},
+			JSONLD: []interface{}{
+				map[string]interface{}{
+					"@context": "https://schema.org",
+					"@type":    "Article",
+					"headline": "Foo",
+				},
+			},
+		}, result.Metadata)
+	}
+}
+
+func TestParse_NoMetadata(t *testing.T) {
+	r := bytes.NewReader([]byte(`<html><body>Hello</body></html>`))
+	if result, err := Parse(r); assert.NoError(t, err) {
+		assert.Equal(t, PageMetadata{}, result.Metadata)
+	}
+}
+
+// language=HTML
+const html4 = `<!DOCTYPE html>
+<html lang="en-GB">
+<head>
+	<base href="http://example.com/">
+	<title>
+		Foo   Bar
+	</title>
+	<meta name="description" content="All about foo">
+	<meta name="robots" content="index, follow">
+	<meta property="og:title" content="Foo">
+	<meta property="og:image" content="http://example.com/1.png">
+	<meta property="og:image" content="http://example.com/2.png">
+	<meta name="twitter:card" content="summary">
+	<meta property="twitter:title" content="Foo">
+	<link rel="alternate" hreflang="de" href="/de/foo">
+	<link rel="alternate" hreflang="x-default" href="foo">
+	<link rel="stylesheet" hreflang="en" href="/style.css">
+	<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Article", "headline": "Foo"}</script>
+	<script type="application/ld+json">{not json}</script>
+</head>
+<body>
+	<h1>Foo</h1>
+	<h2>Bar <em>baz</em></h2>
+	<h3>Qux</h3>
+	<h4>Ignored</h4>
+</body>
+</html>
+`
@@ -0,0 +1,56 @@
+package page_parser
+
+type Option func(p *parser)
+
+// parser holds parsing settings
+type parser struct {
+	contentType   string                  // value of 'Content-Type' http header
+	linkSources   map[LinkSource]struct{} // elements to extract links from, nil for DefaultLinkSources
+	userAgent     string                  // crawler user agent, used to match agent-specific robots meta tags
+	robotsHeaders []string                // values of 'X-Robots-Tag' http headers
+}
+
+// wantSource tells if links from the source should be collected
+func (p *parser) wantSource(source LinkSource) bool {
+	if p.linkSources == nil {
+		for _, s := range DefaultLinkSources {
+			if s == source {
+				return true
+			}
+		}
+		return false
+	}
+	_, ok := p.linkSources[source]
+	return ok
+}
+
+// WithContentType provides 'Content-Type' header value used to determine page character set
+func WithContentType(contentType string) Option {
+	return func(p *parser) {
+		p.contentType = contentType
+	}
+}
+
+// WithLinkSources sets sources to extract links from, only DefaultLinkSources are used by default
+func WithLinkSources(sources ...LinkSource) Option {
+	return func(p *parser) {
+		p.linkSources = make(map[LinkSource]struct{}, len(sources))
+		for _, source := range sources {
+			p.linkSources[source] = struct{}{}
+		}
+	}
+}
+
+// WithUserAgent sets user agent to match agent-specific <meta name="..."> robots tags
+func WithUserAgent(userAgent string) Option {
+	return func(p *parser) {
+		p.userAgent = userAgent
+	}
+}
+
+// WithRobotsHeaders provides 'X-Robots-Tag' http header values to merge into page robots directives
+func WithRobotsHeaders(values ...string) Option {
+	return func(p *parser) {
+		p.robotsHeaders = values
+	}
+}
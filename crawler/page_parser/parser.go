@@ -11,47 +11,86 @@ import (
 // ParsedPage represents contents of a web page
 type ParsedPage struct {
 	// List of collected links
-	Links []string
+	Links []Link
 	// Canonical URL: <link rel="canonical" href="...">
 	CanonicalURL string
+	// Visible page text with collapsed whitespace
+	Text string
+	// Descriptive page data
+	Metadata PageMetadata
+	// Page-level robots directives
+	Robots RobotsDirectives
+	// Character set the page was transcoded from, e.g. "utf-8" or "windows-1251"
+	Charset string
 	// Base URL: <base href="...">
 	baseURL string
 }
 
-func (p *ParsedPage) addLink(link string) {
-	if href := strings.TrimSpace(link); acceptableLink(href) {
-		p.Links = append(p.Links, href)
+func (p *ParsedPage) addLink(link Link) {
+	if link.URL = strings.TrimSpace(link.URL); acceptableLink(link.URL) {
+		p.Links = append(p.Links, link)
 	}
 }
 
+// URLs returns plain list of collected links
+func (p *ParsedPage) URLs() []string {
+	links := make([]string, 0, len(p.Links))
+	for i := range p.Links {
+		links = append(links, p.Links[i].URL)
+	}
+	return links
+}
+
 // resolveLinks tries to convert relative links into absolute ones
 func (p *ParsedPage) resolveLinks() {
 	if p.baseURL == "" {
 		return
 	}
 	if bu, err := url.Parse(p.baseURL); err == nil { // we ignore unparseable base URL
-		links := make([]string, 0, len(p.Links))
+		links := make([]Link, 0, len(p.Links))
 		for i := range p.Links {
-			if lu, err := url.Parse(p.Links[i]); err == nil { // don't handle bad links
-				links = append(links, bu.ResolveReference(lu).String())
+			if lu, err := url.Parse(p.Links[i].URL); err == nil { // don't handle bad links
+				link := p.Links[i]
+				link.URL = bu.ResolveReference(lu).String()
+				links = append(links, link)
 			}
 		}
 		p.Links = links
+		if cu, err := url.Parse(p.CanonicalURL); err == nil && p.CanonicalURL != "" {
+			p.CanonicalURL = bu.ResolveReference(cu).String()
+		}
+		for i := range p.Metadata.Alternates {
+			if au, err := url.Parse(p.Metadata.Alternates[i].URL); err == nil {
+				p.Metadata.Alternates[i].URL = bu.ResolveReference(au).String()
+			}
+		}
 	}
 }
 
 // Parse reads data from provided reader, extracting data
-func Parse(body io.Reader) (*ParsedPage, error) {
+func Parse(body io.Reader, options ...Option) (*ParsedPage, error) {
+	var p parser
+	for _, fn := range options {
+		fn(&p)
+	}
+	var page ParsedPage
+	body, page.Charset = toUTF8(body, p.contentType)
 	doc, err := goquery.NewDocumentFromReader(body)
 	if err != nil {
 		return nil, err
 	}
-	var page ParsedPage
-	doc.Find("a[href]").Each(func(i int, selection *goquery.Selection) {
-		if href, ok := selection.Attr("href"); ok {
-			page.addLink(href)
+	doc.Find(linkSelector).Each(func(i int, selection *goquery.Selection) {
+		if link, ok := extractLink(selection); ok && p.wantSource(link.Source) {
+			page.addLink(link)
 		}
 	})
+	page.Metadata = extractMetadata(doc)
+	page.Text = visibleText(doc)
+	agent := robotsAgent(p.userAgent)
+	page.Robots.applyMeta(doc, agent)
+	for _, value := range p.robotsHeaders {
+		page.Robots.applyHeader(value, agent)
+	}
 	if href, ok := doc.Find(`link[rel=canonical]`).First().Attr("href"); ok {
 		page.CanonicalURL = strings.TrimSpace(href)
 	}
@@ -12,13 +12,29 @@ func TestParse1(t *testing.T) {
 	if result, err := Parse(r); assert.NoError(t, err) {
 		assert.Equal(t, "http://example.com/foo/bar", result.CanonicalURL)
 		assert.Equal(t, "http://example.com/foo/bar/", result.baseURL)
+		assert.Equal(t, "utf-8", result.Charset)
 		assert.Equal(t, []string{
 			"http://example.com/",
 			"http://example.com/foo/bar/page.html",
 			"http://example.com/foo/",
 			"http://some.other.com",
 			"javascript:void(0)",
-		}, result.Links)
+		}, result.URLs())
+	}
+}
+
+func TestParse_RelativeCanonical(t *testing.T) {
+	testCases := map[string]string{
+		` ../bar `: "http://example.com/foo/bar",
+		`baz/`:     "http://example.com/foo/bar/baz/",
+		`/`:        "http://example.com/",
+		`  `:       "",
+	}
+	for href, expected := range testCases {
+		page := `<html><head><base href="http://example.com/foo/bar/"><link rel="canonical" href="` + href + `"></head></html>`
+		if result, err := Parse(bytes.NewReader([]byte(page))); assert.NoError(t, err) {
+			assert.Equal(t, expected, result.CanonicalURL, href)
+		}
 	}
 }
 
@@ -33,7 +49,7 @@ func TestParse2(t *testing.T) {
 			"..",
 			"http://some.other.com",
 			"javascript:void(0)",
-		}, result.Links)
+		}, result.URLs())
 	}
 }
 
@@ -0,0 +1,74 @@
+package page_parser
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// RobotsDirectives contains page-level indexing directives from
+// <meta name="robots"> tags and X-Robots-Tag headers
+type RobotsDirectives struct {
+	// Page should not be indexed
+	NoIndex bool
+	// Links on the page should not be followed
+	NoFollow bool
+}
+
+// Directives that carry a value after colon, used to tell them from user agent prefixes
+var valuedRobotsDirectives = map[string]struct{}{
+	"max-snippet":       {},
+	"max-image-preview": {},
+	"max-video-preview": {},
+	"unavailable_after": {},
+}
+
+// apply merges comma separated list of directives into r
+func (r *RobotsDirectives) apply(content string) {
+	for _, directive := range strings.Split(strings.ToLower(content), ",") {
+		switch strings.TrimSpace(directive) {
+		case "noindex":
+			r.NoIndex = true
+		case "nofollow":
+			r.NoFollow = true
+		case "none":
+			r.NoIndex, r.NoFollow = true, true
+		}
+	}
+}
+
+// applyHeader merges X-Robots-Tag header value into r,
+// values prefixed with a user agent other than agent are skipped;
+// the prefix is a single leading token followed by colon, e.g. "googlebot: noindex"
+func (r *RobotsDirectives) applyHeader(value, agent string) {
+	if i := strings.Index(value, ":"); i > 0 {
+		prefix := strings.ToLower(strings.TrimSpace(value[:i]))
+		if _, ok := valuedRobotsDirectives[prefix]; !ok && !strings.ContainsAny(prefix, ", \t") {
+			if prefix != agent {
+				return
+			}
+			value = value[i+1:]
+		}
+	}
+	r.apply(value)
+}
+
+// applyMeta merges directives from <meta name="robots"> and <meta name="agent"> tags into r
+func (r *RobotsDirectives) applyMeta(doc *goquery.Document, agent string) {
+	doc.Find(`meta[name][content]`).Each(func(i int, s *goquery.Selection) {
+		name := strings.ToLower(strings.TrimSpace(s.AttrOr("name", "")))
+		if name == "robots" || (agent != "" && name == agent) {
+			r.apply(s.AttrOr("content", ""))
+		}
+	})
+}
+
+// robotsAgent returns product token of the user agent as used in meta tags,
+// e.g. "crawlbot" for "CrawlBot/0.1 (+https://example.com/bot)"
+func robotsAgent(userAgent string) string {
+	fields := strings.Fields(userAgent)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.SplitN(fields[0], "/", 2)[0])
+}
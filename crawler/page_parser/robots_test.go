@@ -0,0 +1,79 @@
+package page_parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_RobotsMeta(t *testing.T) {
+	testCases := []struct {
+		name      string
+		html      string
+		userAgent string
+		headers   []string
+		expected  RobotsDirectives
+	}{
+		{
+			name:     "no directives",
+			html:     `<html><head><meta name="description" content="nofollow"></head></html>`,
+			expected: RobotsDirectives{},
+		},
+		{
+			name:     "generic meta",
+			html:     `<html><head><meta name="Robots" content="NoIndex, NoFollow"></head></html>`,
+			expected: RobotsDirectives{NoIndex: true, NoFollow: true},
+		},
+		{
+			name:     "none",
+			html:     `<html><head><meta name="robots" content="none"></head></html>`,
+			expected: RobotsDirectives{NoIndex: true, NoFollow: true},
+		},
+		{
+			name:      "agent-specific meta",
+			html:      `<html><head><meta name="robots" content="all"><meta name="crawlbot" content="nofollow"><meta name="otherbot" content="noindex"></head></html>`,
+			userAgent: "CrawlBot/0.1",
+			expected:  RobotsDirectives{NoFollow: true},
+		},
+		{
+			name:     "header",
+			html:     `<html></html>`,
+			headers:  []string{"noindex"},
+			expected: RobotsDirectives{NoIndex: true},
+		},
+		{
+			name:      "agent-specific headers",
+			html:      `<html></html>`,
+			userAgent: "CrawlBot/0.1",
+			headers:   []string{"otherbot: noindex", "CrawlBot: nofollow", "unavailable_after: 25 Jun 2010 15:00:00 PST"},
+			expected:  RobotsDirectives{NoFollow: true},
+		},
+		{
+			name:      "directive with value after other directives",
+			html:      `<html></html>`,
+			userAgent: "CrawlBot/0.1",
+			headers:   []string{"noindex, unavailable_after: 25 Jun 2010 15:00:00 PST"},
+			expected:  RobotsDirectives{NoIndex: true},
+		},
+	}
+	for _, tt := range testCases {
+		r := bytes.NewReader([]byte(tt.html))
+		if page, err := Parse(r, WithUserAgent(tt.userAgent), WithRobotsHeaders(tt.headers...)); assert.NoError(t, err, tt.name) {
+			assert.Equal(t, tt.expected, page.Robots, tt.name)
+		}
+	}
+}
+
+func TestRobotsAgent(t *testing.T) {
+	assert.Equal(t, "", robotsAgent(""))
+	assert.Equal(t, "crawlbot", robotsAgent("CrawlBot/0.1"))
+	assert.Equal(t, "crawlbot", robotsAgent("CrawlBot/0.1 (+https://example.com/bot)"))
+	assert.Equal(t, "bot", robotsAgent("Bot"))
+}
+
+func TestLinkNoFollow(t *testing.T) {
+	assert.False(t, Link{}.NoFollow())
+	assert.False(t, Link{Rel: []string{"next"}}.NoFollow())
+	assert.True(t, Link{Rel: []string{"ugc", "nofollow"}}.NoFollow())
+}
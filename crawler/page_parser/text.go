@@ -0,0 +1,57 @@
+package page_parser
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Elements whose contents are not visible to the reader
+var invisibleElements = map[string]struct{}{
+	"head":     {},
+	"script":   {},
+	"style":    {},
+	"noscript": {},
+	"template": {},
+	"svg":      {},
+}
+
+// Elements not separating words of the surrounding text
+var inlineElements = map[string]struct{}{
+	"a": {}, "abbr": {}, "b": {}, "bdi": {}, "bdo": {}, "cite": {}, "code": {}, "data": {},
+	"dfn": {}, "em": {}, "font": {}, "i": {}, "kbd": {}, "mark": {}, "q": {}, "s": {},
+	"samp": {}, "small": {}, "span": {}, "strong": {}, "sub": {}, "sup": {}, "time": {},
+	"u": {}, "var": {}, "wbr": {},
+}
+
+// visibleText returns page text as seen by the reader with collapsed whitespace
+func visibleText(doc *goquery.Document) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if _, ok := invisibleElements[n.Data]; ok {
+				return
+			}
+		}
+		_, inline := inlineElements[n.Data]
+		if !inline {
+			b.WriteByte(' ')
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if !inline {
+			b.WriteByte(' ')
+		}
+	}
+	for _, n := range doc.Nodes {
+		walk(n)
+	}
+	return collapseSpaces(b.String())
+}
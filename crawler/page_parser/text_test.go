@@ -0,0 +1,25 @@
+package page_parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Text(t *testing.T) {
+	r := bytes.NewReader([]byte(`<!DOCTYPE html>
+<html>
+<head><title>Not visible</title><style>body {}</style></head>
+<body>
+	<h1>Hello,
+		world</h1>
+	<script>var hidden = true;</script>
+	<noscript>Enable JS</noscript>
+	<p>Some <b>bold</b> text.</p><p>Split<em>ted</em> word</p>
+</body>
+</html>`))
+	if result, err := Parse(r); assert.NoError(t, err) {
+		assert.Equal(t, "Hello, world Some bold text. Splitted word", result.Text)
+	}
+}
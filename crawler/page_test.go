@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+)
+
+func TestCrawler_Process(t *testing.T) {
+	fetcher := &testFetcher{statusCode: 200, html: `<title>T</title><a href="/a">A</a><a href="/b" rel="nofollow">B</a>`}
+	c := New(fetcher, tFilter, nil)
+	expected := newTask(Job{Link: "http://example.com/"}, c.taskSettings()).Process(fetcher).Result()
+	data, err := json.Marshal(c.Process(Job{Link: "http://example.com/"}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	var page Page
+	if assert.NoError(t, json.Unmarshal(data, &page)) {
+		assert.Equal(t, []string{"http://example.com/a"}, page.FollowLinks)
+		result := page.crawlResult().Result()
+		result.FetchDuration = expected.FetchDuration
+		assert.Equal(t, expected, result)
+	}
+}
+
+func TestCrawler_ProcessError(t *testing.T) {
+	c := New(&testFetcher{err: pkgerrors.Wrap(page_fetcher.ErrBodyTooLarge, "get")}, tFilter, nil)
+	data, err := json.Marshal(c.Process(Job{Link: "http://example.com/"}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	var page Page
+	if assert.NoError(t, json.Unmarshal(data, &page)) {
+		result := page.crawlResult().Result()
+		assert.EqualError(t, result.Error, "fetch: get: response body too large")
+		assert.Equal(t, "body_too_large", result.ErrorClass())
+	}
+}
+
+// mapProcessor returns pages by link
+type mapProcessor struct {
+	mu        sync.Mutex
+	pages     map[string]Page
+	processed []string
+}
+
+func (p *mapProcessor) Process(job Job) Page {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processed = append(p.processed, job.Link)
+	page := p.pages[job.Link]
+	page.Link = job.Link
+	return page
+}
+
+func TestCrawler_PageProcessor(t *testing.T) {
+	processor := &mapProcessor{pages: map[string]Page{
+		"http://example.com/": {
+			StatusCode:  200,
+			Links:       []string{"http://example.com/a", "http://example.com/b", "http://example.com/nofollow"},
+			FollowLinks: []string{"http://example.com/a", "http://example.com/b", ""},
+		},
+		"http://example.com/a": {StatusCode: 404, Error: &PageError{Message: "got status code 404", Class: "status"}},
+	}}
+	fetcher := &siteFetcher{}
+	var results []Result
+	c := New(fetcher, tFilter, nil).
+		PageProcessor(processor).
+		MaxPages(10).
+		ResultHandler(func(result Result) {
+			results = append(results, result)
+		})
+	if assert.NoError(t, c.Run("http://example.com/")) {
+		assert.Empty(t, fetcher.urls(), "pages are not fetched by the crawler")
+		sort.Strings(processor.processed)
+		assert.Equal(t, []string{"http://example.com/", "http://example.com/a", "http://example.com/b"}, processor.processed)
+		if assert.Len(t, results, 3) {
+			assert.Equal(t, []string{"http://example.com/a", "http://example.com/b", "http://example.com/nofollow"}, results[0].Links)
+		}
+		assert.Equal(t, uint64(1), c.Stats().Errors)
+	}
+}
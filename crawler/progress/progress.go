@@ -0,0 +1,270 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+	"golang.org/x/term"
+)
+
+const (
+	// How often the view is redrawn
+	refreshInterval = 500 * time.Millisecond
+	// Rates are averaged over this period
+	rateWindow = 10 * time.Second
+	// Oldest requests in flight listed
+	maxShownRequests = 5
+)
+
+// Source is a running crawl, e.g. *crawler.Crawler
+type Source interface {
+	Stats() crawler.Stats
+	Requests() []crawler.Request
+}
+
+// Display shows crawl progress at the bottom of the terminal, refreshed in place;
+// output written through its writers is printed above the view
+type Display struct {
+	out      io.Writer // terminal the view is drawn on
+	maxPages uint64
+	width    func() int       // terminal width in columns, 0 if unknown
+	now      func() time.Time // current time, replaced in tests
+	mu       sync.Mutex       // guards fields below
+	source   Source
+	bytes    int64          // response body bytes read
+	errors   map[string]int // page errors by class
+	samples  []sample       // counters over the rate window, oldest first
+	view     string         // the last drawn view, empty if there is none on the screen
+	lines    int            // lines taken by the view
+	stopC    chan struct{}
+	doneC    chan struct{}
+}
+
+// sample is a snapshot of counters rates are calculated from
+type sample struct {
+	at    time.Time
+	pages uint64
+	bytes int64
+}
+
+// New creates display drawing on out, maxPages of 0 means no limit and no ETA
+func New(out io.Writer, maxPages uint64) *Display {
+	return &Display{
+		out:      out,
+		maxPages: maxPages,
+		width:    func() int { return 0 },
+		now:      time.Now,
+		errors:   make(map[string]int),
+	}
+}
+
+// Width sets function returning terminal width, lines longer than that are cut
+func (d *Display) Width(width func() int) *Display {
+	d.width = width
+	return d
+}
+
+// IsTerminal tells if the file is a terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// TerminalWidth returns function reporting the current width of the terminal, 0 if unknown
+func TerminalWidth(f *os.File) func() int {
+	return func() int {
+		width, _, err := term.GetSize(int(f.Fd()))
+		if err != nil {
+			return 0
+		}
+		return width
+	}
+}
+
+// ObserveResult records page crawl result
+func (d *Display) ObserveResult(result crawler.Result) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.bytes += result.BodySize
+	if class := result.ErrorClass(); class != "" {
+		d.errors[class]++
+	}
+}
+
+// ResultHandler wraps results callback to record every result
+func (d *Display) ResultHandler(next func(crawler.Result)) func(crawler.Result) {
+	return func(result crawler.Result) {
+		d.ObserveResult(result)
+		next(result)
+	}
+}
+
+// Writer returns writer printing above the view, to be used for everything written to the same terminal
+func (d *Display) Writer(w io.Writer) io.Writer {
+	return writer{d: d, w: w}
+}
+
+type writer struct {
+	d *Display
+	w io.Writer
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	w.d.mu.Lock()
+	defer w.d.mu.Unlock()
+	if w.d.view == "" {
+		return w.w.Write(p)
+	}
+	_, _ = io.WriteString(w.d.out, w.d.clear())
+	n, err := w.w.Write(p)
+	_, _ = io.WriteString(w.d.out, w.d.view)
+	return n, err
+}
+
+// Start starts redrawing the view periodically until Stop is called
+func (d *Display) Start(source Source) {
+	d.mu.Lock()
+	d.source = source
+	d.samples = []sample{{at: d.now()}}
+	d.stopC, d.doneC = make(chan struct{}), make(chan struct{})
+	d.mu.Unlock()
+	d.refresh()
+	go func() {
+		defer close(d.doneC)
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.refresh()
+			case <-d.stopC:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops redrawing, the final view is left on the screen and further output goes below it
+func (d *Display) Stop() {
+	close(d.stopC)
+	<-d.doneC
+	d.refresh()
+	d.mu.Lock()
+	d.view, d.lines = "", 0
+	d.mu.Unlock()
+}
+
+// refresh redraws the view with the current numbers
+func (d *Display) refresh() {
+	stats, requests := d.source.Stats(), d.source.Requests()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	lines := d.render(stats, requests, d.now())
+	if width := d.width(); width > 0 {
+		for i := range lines {
+			lines[i] = truncate(lines[i], width-1)
+		}
+	}
+	view := strings.Join(lines, "\n") + "\n"
+	_, _ = io.WriteString(d.out, d.clear()+view)
+	d.view, d.lines = view, len(lines)
+}
+
+// clear returns escape sequence erasing the view, the cursor is expected to be right below it
+func (d *Display) clear() string {
+	if d.lines == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\r\x1b[%dA\x1b[J", d.lines)
+}
+
+// render returns view lines, records rate sample
+func (d *Display) render(stats crawler.Stats, requests []crawler.Request, now time.Time) []string {
+	d.samples = append(d.samples, sample{at: now, pages: stats.Visited, bytes: d.bytes})
+	for len(d.samples) > 2 && now.Sub(d.samples[1].at) >= rateWindow {
+		d.samples = d.samples[1:]
+	}
+	first := d.samples[0]
+	var pagesRate, bytesRate float64
+	if elapsed := now.Sub(first.at).Seconds(); elapsed > 0 {
+		pagesRate = float64(stats.Visited-first.pages) / elapsed
+		bytesRate = float64(d.bytes-first.bytes) / elapsed
+	}
+	pages := fmt.Sprintf("Pages %d", stats.Visited)
+	eta := "-"
+	if d.maxPages > 0 {
+		pages = fmt.Sprintf("Pages %d/%d (%d%%)", stats.Visited, d.maxPages, min(stats.Visited*100/d.maxPages, 100))
+		if stats.Visited < d.maxPages && pagesRate > 0 {
+			remaining := time.Duration(float64(d.maxPages-stats.Visited) / pagesRate * float64(time.Second))
+			eta = remaining.Round(time.Second).String()
+		}
+	}
+	status := fmt.Sprintf("Queued %d | In flight %d/%d | Errors %d",
+		stats.Queued, stats.InFlight, stats.MaxParallelRequests, stats.Errors)
+	if len(d.errors) > 0 {
+		status += " (" + d.errorClasses() + ")"
+	}
+	switch {
+	case stats.Stopping:
+		status += " | Stopping"
+	case stats.Paused:
+		status += " | Paused"
+	}
+	lines := []string{
+		fmt.Sprintf("%s | %.1f pages/s | %s/s | ETA %s", pages, pagesRate, formatBytes(bytesRate), eta),
+		status,
+	}
+	for i, r := range requests {
+		if i == maxShownRequests {
+			lines = append(lines, fmt.Sprintf("  ... and %d more", len(requests)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %6s %s", now.Sub(r.Started).Round(100*time.Millisecond), r.Link))
+	}
+	return lines
+}
+
+// errorClasses lists error counts by class, most frequent first
+func (d *Display) errorClasses() string {
+	classes := make([]string, 0, len(d.errors))
+	for class := range d.errors {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if d.errors[classes[i]] != d.errors[classes[j]] {
+			return d.errors[classes[i]] > d.errors[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+	for i, class := range classes {
+		classes[i] = fmt.Sprintf("%s %d", class, d.errors[class])
+	}
+	return strings.Join(classes, ", ")
+}
+
+// formatBytes returns human-readable size, e.g. "1.5 MiB"
+func formatBytes(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for ; n >= 1024 && i < len(units)-1; i++ {
+		n /= 1024
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
+
+// truncate cuts the line to the given number of characters
+func truncate(line string, width int) string {
+	runes := []rune(line)
+	if width < 0 || len(runes) <= width {
+		return line
+	}
+	return string(runes[:width])
+}
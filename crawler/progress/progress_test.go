@@ -0,0 +1,113 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler"
+)
+
+type tSource struct {
+	mu       sync.Mutex
+	stats    crawler.Stats
+	requests []crawler.Request
+}
+
+func (s *tSource) Stats() crawler.Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+func (s *tSource) Requests() []crawler.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func TestDisplay_Render(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := New(nil, 100)
+	d.samples = []sample{{at: start}}
+	handler := d.ResultHandler(func(crawler.Result) {})
+	handler(crawler.Result{StatusCode: 200, BodySize: 15 * 1024})
+	handler(crawler.Result{StatusCode: 404, Error: errors.New("got status code 404")})
+	handler(crawler.Result{StatusCode: 500, Error: errors.New("got status code 500")})
+	handler(crawler.Result{StatusCode: 200, Error: errors.New("parse: bad markup")})
+	now := start.Add(2 * time.Second)
+	requests := []crawler.Request{
+		{Link: "http://example.com/slow", Started: now.Add(-2100 * time.Millisecond)},
+		{Link: "http://example.com/fast", Started: now.Add(-400 * time.Millisecond)},
+	}
+	stats := crawler.Stats{Visited: 20, Queued: 7, InFlight: 2, Errors: 3, MaxParallelRequests: 4, Paused: true}
+	assert.Equal(t, []string{
+		"Pages 20/100 (20%) | 10.0 pages/s | 7.5 KiB/s | ETA 8s",
+		"Queued 7 | In flight 2/4 | Errors 3 (status 2, other 1) | Paused",
+		"    2.1s http://example.com/slow",
+		"   400ms http://example.com/fast",
+	}, d.render(stats, requests, now))
+}
+
+func TestDisplay_RenderRateWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := New(nil, 0)
+	d.samples = []sample{{at: start}}
+	d.render(crawler.Stats{Visited: 50}, nil, start.Add(time.Second))
+	d.render(crawler.Stats{Visited: 60}, nil, start.Add(11*time.Second))
+	// The first second of the crawl is out of the window now
+	lines := d.render(crawler.Stats{Visited: 70}, nil, start.Add(21*time.Second))
+	assert.Equal(t, "Pages 70 | 1.0 pages/s | 0 B/s | ETA -", lines[0])
+	var requests []crawler.Request
+	for i := 0; i < maxShownRequests+2; i++ {
+		requests = append(requests, crawler.Request{Link: "http://example.com/", Started: start})
+	}
+	lines = d.render(crawler.Stats{Visited: 100, Stopping: true, Paused: true}, requests, start.Add(22*time.Second))
+	assert.Equal(t, "Queued 0 | In flight 0/0 | Errors 0 | Stopping", lines[1])
+	assert.Len(t, lines, 2+maxShownRequests+1)
+	assert.Equal(t, "  ... and 2 more", lines[len(lines)-1])
+}
+
+func TestDisplay_Writer(t *testing.T) {
+	var terminal bytes.Buffer
+	source := &tSource{stats: crawler.Stats{Visited: 1, MaxParallelRequests: 1}}
+	d := New(&terminal, 10).Width(func() int { return 20 })
+	log := d.Writer(&terminal)
+	_, _ = log.Write([]byte("before\n"))
+	assert.Equal(t, "before\n", terminal.String())
+	terminal.Reset()
+	d.Start(source)
+	view := "Pages 1/10 (10%) | \nQueued 0 | In fligh\n"
+	assert.Equal(t, view, terminal.String())
+	terminal.Reset()
+	_, _ = log.Write([]byte("line\n"))
+	// The view is erased, the line is printed and the view is drawn again below it
+	assert.Equal(t, "\r\x1b[2A\x1b[J"+"line\n"+view, terminal.String())
+	d.Stop()
+	terminal.Reset()
+	_, _ = log.Write([]byte("after\n"))
+	assert.Equal(t, "after\n", terminal.String())
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if assert.NoError(t, err) {
+		defer f.Close()
+		assert.False(t, IsTerminal(f))
+		assert.Zero(t, TerminalWidth(f)())
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "2.0 GiB", formatBytes(2<<30))
+	assert.Equal(t, "4096.0 GiB", formatBytes(4<<40))
+	assert.True(t, strings.HasPrefix(truncate("ÿÿÿ", 2), "ÿÿ"))
+}
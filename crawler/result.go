@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/fingerprint"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/dmitry-vovk/wcrawler/crawler/page_parser"
+)
+
+// Result describes the outcome of a single page crawl
+type Result struct {
+	// Page URL
+	Link string
+	// Response status code, 0 if no response was received
+	StatusCode int
+	// Redirects followed to get the page, in order
+	Redirects []page_fetcher.Redirect
+	// Time it took to get response headers, including redirects
+	FetchDuration time.Duration
+	// Response body bytes read
+	BodySize int64
+	// Canonical page URL, resolved and normalised, empty if not set
+	CanonicalLink string
+	// Canonical URL had been seen before the page was processed, its links were not followed
+	CanonicalDuplicate bool
+	// Unique links found on the page, sorted
+	Links []string
+	// Links in the order of appearance with anchor text, attributes and position on the page
+	PageLinks []page_parser.Link
+	// Page asked not to be indexed via robots meta tag or X-Robots-Tag header
+	NoIndex bool
+	// Page title, headings and other descriptive data, nil if the page was not parsed
+	Metadata *page_parser.PageMetadata
+	// Page content fingerprint, nil if the page was not parsed
+	Fingerprint *fingerprint.Fingerprint
+	// Visible page text with collapsed whitespace
+	Text string
+	// Previously crawled page this one is a near-duplicate of, empty if none
+	NearDuplicateOf string
+	// Error processing the page, if any
+	Error error
+}
+
+// ErrorClass classifies page error: status, content_type, body_too_large, too_slow, timeout, network or other;
+// empty if there was no error
+func (r Result) ErrorClass() string {
+	var netErr net.Error
+	var classified interface{ ErrorClass() string }
+	switch {
+	case r.Error == nil:
+		return ""
+	case errors.As(r.Error, &classified):
+		return classified.ErrorClass()
+	case r.StatusCode != 0 && r.StatusCode != http.StatusOK:
+		return "status"
+	case errors.Is(r.Error, page_fetcher.ErrBadContentType):
+		return "content_type"
+	case errors.Is(r.Error, page_fetcher.ErrBodyTooLarge):
+		return "body_too_large"
+	case errors.Is(r.Error, page_fetcher.ErrTooSlow):
+		return "too_slow"
+	case errors.As(r.Error, &netErr) && netErr.Timeout():
+		return "timeout"
+	case netErr != nil:
+		return "network"
+	}
+	return "other"
+}
@@ -0,0 +1,35 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+)
+
+func TestResult_ErrorClass(t *testing.T) {
+	timeout := &url.Error{Op: "Get", URL: "http://example.com/", Err: context.DeadlineExceeded}
+	refused := &url.Error{Op: "Get", URL: "http://example.com/", Err: errors.New("connection refused")}
+	testCases := []struct {
+		result   Result
+		expected string
+	}{
+		{result: Result{StatusCode: 200}, expected: ""},
+		{result: Result{StatusCode: 500, Error: errors.New("got status code 500")}, expected: "status"},
+		{result: Result{StatusCode: 200, Error: pkgerrors.Wrap(page_fetcher.ErrBadContentType, "fetch")}, expected: "content_type"},
+		{result: Result{Error: pkgerrors.Wrap(page_fetcher.ErrBodyTooLarge, "fetch")}, expected: "body_too_large"},
+		{result: Result{StatusCode: 200, Error: pkgerrors.Wrap(page_fetcher.ErrTooSlow, "parse")}, expected: "too_slow"},
+		{result: Result{Error: pkgerrors.Wrap(timeout, "fetch")}, expected: "timeout"},
+		{result: Result{Error: pkgerrors.Wrap(refused, "fetch")}, expected: "network"},
+		{result: Result{StatusCode: 200, Error: errors.New("parse: bad markup")}, expected: "other"},
+	}
+	for _, tt := range testCases {
+		assert.Equal(t, tt.expected, tt.result.ErrorClass(), fmt.Sprint(tt.result.Error))
+	}
+}
@@ -0,0 +1,182 @@
+package robots_txt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Decision explains robots.txt verdict on a URL
+type Decision struct {
+	// robots.txt location
+	RobotsURL string
+	// robots.txt response status, 0 if the host was unreachable
+	Status int
+	// Whether the URL is allowed to be visited
+	Allowed bool
+	// User agent of the group that applies, empty if no group does
+	Group string
+	// Number of the deciding line, starting with 1, 0 if no rule matches
+	Line int
+	// Deciding line as written in robots.txt
+	Rule string
+	// Whether the deciding line could not be told, robots.txt package reads the file differently
+	RuleUnknown bool
+}
+
+func (d Decision) String() string {
+	verdict := "disallowed"
+	if d.Allowed {
+		verdict = "allowed"
+	}
+	source := fmt.Sprintf("%s (status %d)", d.RobotsURL, d.Status)
+	if d.Status == 0 {
+		source = d.RobotsURL + " (unreachable)"
+	}
+	switch {
+	case d.RuleUnknown:
+		return fmt.Sprintf("%s, deciding line unknown, in %s", verdict, source)
+	case d.Line > 0:
+		return fmt.Sprintf("%s by line %d %q for user-agent %q in %s", verdict, d.Line, d.Rule, d.Group, source)
+	case d.Group != "":
+		return fmt.Sprintf("%s, no rule for user-agent %q matches in %s", verdict, d.Group, source)
+	}
+	return fmt.Sprintf("%s, no rules apply in %s", verdict, source)
+}
+
+// ExplainURL describes robots.txt decision on the URL, used by url_filter
+func (m *Manager) ExplainURL(u *url.URL, agent string) string {
+	return m.Explain(u, agent).String()
+}
+
+// Explain tells if the URL is allowed to be visited by the agent and which robots.txt line decides that
+func (m *Manager) Explain(u *url.URL, agent string) Decision {
+	e := m.lookup(u)
+	path := u.RequestURI()
+	d := Decision{
+		RobotsURL: (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String(),
+		Status:    e.status,
+		Allowed:   e.data.TestAgent(path, agent),
+	}
+	if e.body != nil {
+		var allow bool
+		d.Group, d.Line, d.Rule, allow = findRule(e.body, agent, path)
+		// Matching is repeated here to find the line, the verdict and group of robotstxt package are authoritative
+		sameGroup := d.Group == "" || e.data.FindGroup(d.Group) == e.data.FindGroup(agent)
+		if !sameGroup || allow != d.Allowed {
+			d.Group, d.Line, d.Rule, d.RuleUnknown = "", 0, "", true
+		}
+	}
+	return d
+}
+
+// ruleLine is an allow or disallow line of robots.txt
+type ruleLine struct {
+	n       int
+	text    string
+	allow   bool
+	path    string
+	pattern *regexp.Regexp
+}
+
+// findRule repeats group and rule selection of robotstxt package, returning the deciding line
+// and whether the path is allowed according to it
+func findRule(body []byte, agent, path string) (group string, n int, text string, allow bool) {
+	groups := parseRuleLines(body)
+	agent = strings.ToLower(agent)
+	if _, ok := groups["*"]; ok {
+		group = "*"
+	}
+	for a := range groups {
+		if a != "*" && strings.HasPrefix(agent, a) && len(a) > len(group) {
+			group = a
+		}
+	}
+	var prefixLen int
+	var found *ruleLine
+	for _, r := range groups[group] {
+		switch {
+		case r.pattern != nil:
+			if r.pattern.MatchString(path) && len(r.pattern.String()) > prefixLen {
+				prefixLen, found = len(r.pattern.String()), r
+			}
+		case r.path == "/" && prefixLen == 0:
+			prefixLen, found = 1, r
+		case strings.HasPrefix(path, r.path) && len(r.path) > prefixLen:
+			prefixLen, found = len(r.path), r
+		}
+	}
+	if found == nil {
+		// No restrictions by default
+		return group, 0, "", true
+	}
+	return group, found.n, found.text, found.allow
+}
+
+// parseRuleLines groups allow and disallow lines by lowercase user agent
+func parseRuleLines(body []byte) map[string][]*ruleLine {
+	groups := make(map[string][]*ruleLine)
+	var agents []string
+	emptyGroup := true
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent", "useragent":
+			if !emptyGroup {
+				agents, emptyGroup = nil, true
+			}
+			if value != "" {
+				agents = append(agents, strings.ToLower(value))
+			}
+		case "allow", "disallow":
+			r := newRuleLine(n, strings.TrimSpace(line), key == "allow", value)
+			if r == nil || len(agents) == 0 {
+				continue
+			}
+			emptyGroup = false
+			for _, a := range agents {
+				groups[a] = append(groups[a], r)
+			}
+		case "crawl-delay", "crawldelay":
+			if len(agents) > 0 {
+				emptyGroup = false
+			}
+		}
+	}
+	return groups
+}
+
+// newRuleLine converts rule path the way robotstxt package does, nil for empty paths
+func newRuleLine(n int, text string, allow bool, path string) *ruleLine {
+	if path == "" {
+		return nil
+	}
+	if !strings.HasPrefix(path, "*") && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	path = strings.TrimRight(path, "*")
+	r := ruleLine{n: n, text: text, allow: allow, path: path}
+	if strings.ContainsAny(path, "*$") {
+		pattern := regexp.QuoteMeta(path)
+		pattern = strings.ReplaceAll(pattern, `\*`, `.*`)
+		pattern = strings.ReplaceAll(pattern, `\$`, `$`)
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil
+		}
+		r.pattern = compiled
+	}
+	return &r
+}
@@ -0,0 +1,116 @@
+package robots_txt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	f := &testFetcher{responses: map[string]testResponse{
+		"http://example.com/robots.txt": {status: 200, body: `# comment
+User-agent: *
+Disallow: /private # secret
+Allow: /private/ok
+
+User-agent: BotX
+User-agent: BotY
+Disallow: /*.pdf$
+Disallow:
+`},
+		"http://down.example.com/robots.txt": {status: 503},
+		// robotstxt package accepts a rule without colon, the explaining parser does not
+		"http://odd.example.com/robots.txt": {status: 200, body: "User-agent: *\nDisallow /private\n"},
+	}}
+	m := NewManager(f, mustParse("http://example.com/"))
+	testCases := []struct {
+		link     string
+		agent    string
+		expected Decision
+		text     string
+	}{
+		{
+			link:  "http://example.com/private/x",
+			agent: "Bot",
+			expected: Decision{
+				RobotsURL: "http://example.com/robots.txt",
+				Status:    200,
+				Group:     "*",
+				Line:      3,
+				Rule:      "Disallow: /private",
+			},
+			text: `disallowed by line 3 "Disallow: /private" for user-agent "*" in http://example.com/robots.txt (status 200)`,
+		},
+		{
+			link:  "http://example.com/private/ok.html",
+			agent: "Bot",
+			expected: Decision{
+				RobotsURL: "http://example.com/robots.txt",
+				Status:    200,
+				Allowed:   true,
+				Group:     "*",
+				Line:      4,
+				Rule:      "Allow: /private/ok",
+			},
+		},
+		{
+			link:  "http://example.com/private/doc.pdf",
+			agent: "BotY/2.0",
+			expected: Decision{
+				RobotsURL: "http://example.com/robots.txt",
+				Status:    200,
+				Group:     "boty",
+				Line:      8,
+				Rule:      "Disallow: /*.pdf$",
+			},
+		},
+		{
+			link:  "http://example.com/private/",
+			agent: "BotX",
+			expected: Decision{
+				RobotsURL: "http://example.com/robots.txt",
+				Status:    200,
+				Allowed:   true,
+				Group:     "botx",
+			},
+			text: `allowed, no rule for user-agent "botx" matches in http://example.com/robots.txt (status 200)`,
+		},
+		{
+			link:  "http://other.com/",
+			agent: "Bot",
+			expected: Decision{
+				RobotsURL: "http://other.com/robots.txt",
+				Status:    404,
+				Allowed:   true,
+			},
+			text: "allowed, no rules apply in http://other.com/robots.txt (status 404)",
+		},
+		{
+			link:  "http://down.example.com/",
+			agent: "Bot",
+			expected: Decision{
+				RobotsURL: "http://down.example.com/robots.txt",
+				Status:    503,
+			},
+			text: "disallowed, no rules apply in http://down.example.com/robots.txt (status 503)",
+		},
+		{
+			link:  "http://odd.example.com/private",
+			agent: "Bot",
+			expected: Decision{
+				RobotsURL:   "http://odd.example.com/robots.txt",
+				Status:      200,
+				RuleUnknown: true,
+			},
+			text: "disallowed, deciding line unknown, in http://odd.example.com/robots.txt (status 200)",
+		},
+	}
+	for _, tt := range testCases {
+		d := m.Explain(mustParse(tt.link), tt.agent)
+		assert.Equal(t, tt.expected, d, tt.link)
+		assert.Equal(t, m.TestURL(mustParse(tt.link), tt.agent), d.Allowed, tt.link)
+		if tt.text != "" {
+			assert.Equal(t, tt.text, d.String(), tt.link)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package robots_txt
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/dmitry-vovk/wcrawler/crawler/types"
+	"github.com/temoto/robotstxt"
+)
+
+const (
+	// How long successfully fetched robots.txt is kept
+	defaultExpiry = 24 * time.Hour
+	// How long to wait before retrying after a server error or network failure
+	defaultRetryExpiry = time.Minute
+	// Content after this size is ignored, as Google does
+	maxRobotsSize = 500 << 10
+)
+
+// Manager fetches robots.txt lazily for every host and caches it
+// see https://developers.google.com/search/reference/robots_txt#handling-http-result-codes
+type Manager struct {
+	fetcher     types.Fetcher
+	base        *url.URL         // host used for TestAgent calls
+	expiry      time.Duration    // how long to keep fetched robots.txt
+	retryExpiry time.Duration    // how long to keep failed fetch outcome
+	now         func() time.Time // clock, replaced in tests
+	logger      *slog.Logger
+	mu          sync.Mutex        // guards hosts
+	hosts       map[string]*entry // robots.txt by scheme and host
+}
+
+// entry is a robots.txt state of a single host
+type entry struct {
+	ready   chan struct{} // closed once the fetch is complete
+	data    *robotstxt.RobotsData
+	body    []byte // robots.txt contents the data was parsed from
+	status  int    // response status code, 0 if the host was unreachable
+	good    bool   // whether data came from a definitive response
+	expires time.Time
+}
+
+// NewManager creates robots.txt manager, base URL is the host TestAgent checks paths against
+func NewManager(fetcher types.Fetcher, base *url.URL, options ...Option) *Manager {
+	m := Manager{
+		fetcher: fetcher,
+		base:    base,
+		now:     time.Now,
+		hosts:   make(map[string]*entry),
+		logger:  slog.Default(),
+	}
+	for _, fn := range options {
+		fn(&m)
+	}
+	if m.expiry == 0 {
+		m.expiry = defaultExpiry
+	}
+	if m.retryExpiry == 0 {
+		m.retryExpiry = defaultRetryExpiry
+	}
+	return &m
+}
+
+// TestAgent tells if the path on the base host is allowed to be visited by the agent
+func (m *Manager) TestAgent(path, agent string) bool {
+	u := *m.base
+	u.Path, u.RawPath, u.RawQuery = path, "", ""
+	return m.TestURL(&u, agent)
+}
+
+// TestURL tells if the URL is allowed to be visited by the agent, fetching robots.txt of its host if necessary
+func (m *Manager) TestURL(u *url.URL, agent string) bool {
+	return m.Get(u).TestAgent(u.RequestURI(), agent)
+}
+
+// Get returns robots.txt rules for the host of the URL
+func (m *Manager) Get(u *url.URL) *robotstxt.RobotsData {
+	return m.lookup(u).data
+}
+
+// lookup returns fresh host entry, only one fetch per host is done at a time
+func (m *Manager) lookup(u *url.URL) *entry {
+	key := u.Scheme + "://" + u.Host
+	m.mu.Lock()
+	previous := m.hosts[key]
+	if previous != nil && !m.expired(previous) {
+		m.mu.Unlock()
+		<-previous.ready
+		return previous
+	}
+	e := &entry{ready: make(chan struct{})}
+	m.hosts[key] = e
+	m.mu.Unlock()
+	m.fetch(&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}, e, previous)
+	close(e.ready)
+	return e
+}
+
+// expired tells if the entry has to be fetched again, entries being fetched are never expired
+func (m *Manager) expired(e *entry) bool {
+	select {
+	case <-e.ready:
+		return m.now().After(e.expires)
+	default:
+		return false
+	}
+}
+
+// fetch gets robots.txt and fills the entry according to the response status,
+// last good copy is kept when the host is temporarily unavailable
+func (m *Manager) fetch(link *url.URL, e *entry, previous *entry) {
+	body, status, err := m.download(link)
+	switch {
+	case errors.Is(err, page_fetcher.ErrBadContentType) || errors.Is(err, page_fetcher.ErrBodyTooLarge):
+		// The fetcher refused the file, the host is reachable so it is not a reason to disallow everything
+		m.logger.Warn("robots.txt rejected by the fetcher, no restrictions applied", "url", link.String(), "host", link.Host,
+			"error", err)
+	case err != nil || status == http.StatusTooManyRequests || status >= 500:
+		attrs := []any{"url", link.String(), "host", link.Host, "status", status}
+		if err != nil {
+			attrs = append(attrs, "error", err)
+		}
+		m.logger.Warn("Error fetching robots.txt", attrs...)
+		e.status, e.expires = status, m.now().Add(m.retryExpiry)
+		if previous != nil && previous.good {
+			e.data, e.body, e.good = previous.data, previous.body, true
+		} else {
+			// Temporary errors result in a full disallow
+			e.data, _ = robotstxt.FromStatusAndBytes(http.StatusServiceUnavailable, nil)
+		}
+		return
+	case status >= 200 && status < 300:
+		e.body = body
+		e.data, err = robotstxt.FromBytes(body)
+		if err != nil {
+			m.logger.Warn("Error parsing robots.txt", "url", link.String(), "host", link.Host, "error", err)
+			e.data, e.body = nil, nil
+		}
+	}
+	if e.data == nil {
+		// 4xx, unresolved redirects and unparseable files mean there are no restrictions
+		e.data, _ = robotstxt.FromStatusAndBytes(http.StatusNotFound, nil)
+	}
+	e.status, e.good, e.expires = status, true, m.now().Add(m.expiry)
+}
+
+// download fetches robots.txt body, redirects are followed by the fetcher
+func (m *Manager) download(link *url.URL) ([]byte, int, error) {
+	resp, err := m.fetcher.Fetch(&page_fetcher.Request{URL: link})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, resp.StatusCode, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if errors.Is(err, page_fetcher.ErrBodyTooLarge) {
+		// Content read so far is used, as if the file was cut at the size limit
+		m.logger.Warn("robots.txt truncated", "url", link.String(), "host", link.Host, "size", len(body))
+		err = nil
+	}
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	return body, resp.StatusCode, nil
+}
@@ -0,0 +1,181 @@
+package robots_txt
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagerStatuses(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  int
+		err     error
+		private bool
+		public  bool
+	}{
+		{name: "ok", status: 200, private: false, public: true},
+		{name: "not found", status: 404, private: true, public: true},
+		{name: "forbidden", status: 403, private: true, public: true},
+		{name: "too many requests", status: 429, private: false, public: false},
+		{name: "server error", status: 503, private: false, public: false},
+		{name: "unreachable", err: errors.New("connection refused"), private: false, public: false},
+		{name: "bad content type", err: page_fetcher.ErrBadContentType, private: true, public: true},
+		{name: "too large", err: page_fetcher.ErrBodyTooLarge, private: true, public: true},
+	}
+	for _, tt := range testCases {
+		f := &testFetcher{responses: map[string]testResponse{
+			"http://example.com/robots.txt": {status: tt.status, body: "User-agent: *\nDisallow: /private", err: tt.err},
+		}}
+		m := NewManager(f, mustParse("http://example.com/"))
+		assert.Equal(t, tt.private, m.TestAgent("/private/page", "Bot"), tt.name)
+		assert.Equal(t, tt.public, m.TestAgent("/public", "Bot"), tt.name)
+	}
+}
+
+func TestManagerTruncated(t *testing.T) {
+	f := &testFetcher{responses: map[string]testResponse{
+		"http://example.com/robots.txt": {status: 200, body: "User-agent: *\nDisallow: /private", truncated: true},
+	}}
+	m := NewManager(f, mustParse("http://example.com/"))
+	assert.False(t, m.TestAgent("/private/page", "Bot"), "rules read before the limit apply")
+	assert.True(t, m.TestAgent("/public", "Bot"))
+}
+
+func TestManagerPerHost(t *testing.T) {
+	f := &testFetcher{responses: map[string]testResponse{
+		"http://example.com/robots.txt":      {status: 200, body: "User-agent: *\nDisallow: /a"},
+		"https://sub.example.com/robots.txt": {status: 200, body: "User-agent: *\nDisallow: /b"},
+	}}
+	m := NewManager(f, mustParse("http://example.com/"))
+	assert.False(t, m.TestURL(mustParse("http://example.com/a"), "Bot"))
+	assert.True(t, m.TestURL(mustParse("http://example.com/b"), "Bot"))
+	assert.True(t, m.TestURL(mustParse("https://sub.example.com/a"), "Bot"))
+	assert.False(t, m.TestURL(mustParse("https://sub.example.com/b?q=1"), "Bot"))
+	// Unknown host responds with 404
+	assert.True(t, m.TestURL(mustParse("http://other.com/a"), "Bot"))
+	assert.Equal(t, 1, f.count("http://example.com/robots.txt"))
+	assert.Equal(t, 1, f.count("https://sub.example.com/robots.txt"))
+}
+
+func TestManagerQuery(t *testing.T) {
+	f := &testFetcher{responses: map[string]testResponse{
+		"http://example.com/robots.txt": {status: 200, body: "User-agent: *\nDisallow: /*?sort="},
+	}}
+	m := NewManager(f, mustParse("http://example.com/"))
+	assert.True(t, m.TestURL(mustParse("http://example.com/list"), "Bot"))
+	assert.False(t, m.TestURL(mustParse("http://example.com/list?sort=asc"), "Bot"))
+}
+
+func TestManagerExpiry(t *testing.T) {
+	f := &testFetcher{responses: map[string]testResponse{
+		"http://example.com/robots.txt": {status: 200, body: "User-agent: *\nDisallow: /a"},
+	}}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewManager(f, mustParse("http://example.com/"), WithExpiry(time.Hour), WithRetryExpiry(time.Minute))
+	m.now = func() time.Time { return now }
+	assert.False(t, m.TestAgent("/a", "Bot"))
+	now = now.Add(30 * time.Minute)
+	assert.False(t, m.TestAgent("/a", "Bot"))
+	assert.Equal(t, 1, f.count("http://example.com/robots.txt"))
+	// Server error on refetch keeps the last good copy
+	f.set("http://example.com/robots.txt", testResponse{status: 500})
+	now = now.Add(time.Hour)
+	assert.False(t, m.TestAgent("/a", "Bot"))
+	assert.True(t, m.TestAgent("/b", "Bot"))
+	assert.Equal(t, 2, f.count("http://example.com/robots.txt"))
+	// Failures are retried sooner
+	f.set("http://example.com/robots.txt", testResponse{status: 404})
+	now = now.Add(2 * time.Minute)
+	assert.True(t, m.TestAgent("/a", "Bot"))
+	assert.Equal(t, 3, f.count("http://example.com/robots.txt"))
+}
+
+func TestManagerConcurrentFetch(t *testing.T) {
+	f := &testFetcher{
+		responses: map[string]testResponse{
+			"http://example.com/robots.txt": {status: 200, body: "User-agent: *\nDisallow: /a"},
+		},
+		delay: 50 * time.Millisecond,
+	}
+	m := NewManager(f, mustParse("http://example.com/"))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.False(t, m.TestAgent("/a", "Bot"))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, f.count("http://example.com/robots.txt"))
+}
+
+type testResponse struct {
+	status    int
+	body      string
+	err       error
+	truncated bool // body read fails with ErrBodyTooLarge after the content
+}
+
+type testFetcher struct {
+	mu        sync.Mutex
+	responses map[string]testResponse
+	requests  map[string]int
+	delay     time.Duration
+}
+
+func (f *testFetcher) Fetch(r *page_fetcher.Request) (*page_fetcher.Response, error) {
+	time.Sleep(f.delay)
+	link := r.URL.String()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.requests == nil {
+		f.requests = make(map[string]int)
+	}
+	f.requests[link]++
+	resp, ok := f.responses[link]
+	if !ok {
+		resp = testResponse{status: 404}
+	}
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	var body io.Reader = strings.NewReader(resp.body)
+	if resp.truncated {
+		body = io.MultiReader(body, iotest.ErrReader(page_fetcher.ErrBodyTooLarge))
+	}
+	return &page_fetcher.Response{
+		URL:        r.URL,
+		StatusCode: resp.status,
+		Body:       io.NopCloser(body),
+	}, nil
+}
+
+func (f *testFetcher) set(link string, resp testResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[link] = resp
+}
+
+func (f *testFetcher) count(link string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[link]
+}
+
+func mustParse(link string) *url.URL {
+	u, err := url.Parse(link)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
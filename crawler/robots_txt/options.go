@@ -0,0 +1,30 @@
+package robots_txt
+
+import (
+	"log/slog"
+	"time"
+)
+
+type Option func(m *Manager)
+
+// WithExpiry sets how long fetched robots.txt is cached
+func WithExpiry(expiry time.Duration) Option {
+	return func(m *Manager) {
+		m.expiry = expiry
+	}
+}
+
+// WithRetryExpiry sets how long to wait before fetching robots.txt again
+// after a server error or network failure
+func WithRetryExpiry(expiry time.Duration) Option {
+	return func(m *Manager) {
+		m.retryExpiry = expiry
+	}
+}
+
+// WithLogger sets logger for robots.txt fetch and parse errors
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Manager) {
+		m.logger = logger
+	}
+}
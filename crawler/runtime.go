@@ -1,9 +1,10 @@
 package crawler
 
 import (
-	"log"
 	"sync/atomic"
 	"time"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/url_filter"
 )
 
 // processor sequentially processes page crawls
@@ -12,18 +13,12 @@ out:
 	for {
 		select {
 		case job := <-c.queuedLinksC:
-			if _, ok := c.processedLinks[job.Link]; !ok {
-				c.processingLinks[job.Link] = struct{}{}
-				c.processedLinks[job.Link] = struct{}{}
-				go c.processJob(job)
-			}
+			c.enqueue(job)
 		case result := <-c.processedLinksC:
-			if result.CanonicalLink != "" {
-				c.processedLinks[result.CanonicalLink] = struct{}{}
-			}
 			delete(c.processingLinks, result.Link)
-			if c.resultCallback != nil {
-				c.resultCallback(result.Link, result.CollectLinks())
+			if !result.Skipped {
+				atomic.AddUint64(&c.pagesN, 1)
+				c.handleResult(&result)
 			}
 			if atomic.LoadUint64(&c.pagesN) >= c.maxPages {
 				break out
@@ -33,29 +28,209 @@ out:
 			}
 		}
 	}
-	log.Printf("Pages visited: %d", c.pagesN)
+	c.logger.Info("Crawl finished", "pages", atomic.LoadUint64(&c.pagesN))
+	// Jobs waiting for a request slot are turned away instead of fetching pages nobody waits for
+	c.limiter.stop()
+	c.mu.Lock()
+	c.running = false
+	c.mu.Unlock()
 	close(c.doneC)
 	c.finished = true
 }
 
+// handleResult queues links found on the page and passes the result to callbacks
+func (c *Crawler) handleResult(result *crawlResult) {
+	c.handleCanonical(result)
+	c.handleDuplicate(result)
+	for i := range result.QueueLinks {
+		c.enqueue(Job{Link: result.QueueLinks[i], Referrer: result.Link})
+	}
+	if c.resultCallback != nil {
+		c.resultCallback(result.Link, result.CollectLinks())
+	}
+	if c.resultHandler != nil {
+		c.resultHandler(result.Result())
+	}
+}
+
+// enqueue starts processing the job unless its link has been seen already
+func (c *Crawler) enqueue(job Job) {
+	if c.markVisited(job.Link) {
+		c.processingLinks[job.Link] = struct{}{}
+		atomic.AddInt64(&c.queuedN, 1)
+		if c.jobQueue != nil {
+			c.queueJob(job)
+		} else {
+			go c.processJob(job)
+		}
+	}
+}
+
+// queueJob hands the job to the job queue, the queue decides when it is processed
+func (c *Crawler) queueJob(job Job) {
+	var start time.Time
+	c.jobQueue.Queue(job, func() bool {
+		atomic.AddInt64(&c.queuedN, -1)
+		if _, _, stopped := c.limiter.state(); stopped || c.excluded(job.Link) {
+			// The queue may be holding a lock the processor needs
+			go c.skipJob(job)
+			return false
+		}
+		start = time.Now()
+		c.beginRequest(job)
+		return true
+	}, func(p Page) {
+		c.finishJob(job, start, p.crawlResult())
+	})
+}
+
+// handleCanonical records page canonical URL if collected; links of pages whose canonical URL has been seen
+// before are not followed if configured so, the canonical URL is queued instead.
+// Seen canonicals are kept apart from visited links, so the canonical page itself is still crawled
+func (c *Crawler) handleCanonical(result *crawlResult) {
+	if c.canonicals != nil && result.CanonicalLink != "" {
+		c.canonicals.add(result.Link, result.CanonicalLink)
+	}
+	if !c.skipCanonicalDuplicates {
+		return
+	}
+	if result.CanonicalLink == "" || result.CanonicalLink == result.Link {
+		// The page is canonical itself
+		c.markCanonicalSeen(result.Link)
+		return
+	}
+	if !c.markCanonicalSeen(result.CanonicalLink) {
+		c.logger.Debug("Not following links: canonical URL already seen", "url", result.Link, "canonical", result.CanonicalLink)
+		result.CanonicalDuplicate = true
+		result.QueueLinks = nil
+		if result.canonicalInScope && !c.excluded(result.CanonicalLink) {
+			result.QueueLinks = []string{result.CanonicalLink}
+		}
+	}
+}
+
+// markCanonicalSeen records the canonical URL as seen, returns false if it has been seen already
+func (c *Crawler) markCanonicalSeen(link string) bool {
+	added, err := c.seenCanonicals.Add(link)
+	if err != nil {
+		// Following links of a duplicate is better than missing them
+		c.logger.Error("Error recording seen canonical URL", "url", link, "error", err)
+		return true
+	}
+	return added
+}
+
+// markVisited records the link as visited, returns false if it has been visited already
+func (c *Crawler) markVisited(link string) bool {
+	added, err := c.visited.Add(link)
+	if err != nil {
+		// Visiting a link twice is better than missing it
+		c.logger.Error("Error recording visited link", "url", link, "error", err)
+		return true
+	}
+	return added
+}
+
+// handleDuplicate checks page content against previously crawled pages;
+// links of near-duplicate pages are not followed if configured so
+func (c *Crawler) handleDuplicate(result *crawlResult) {
+	if result.Fingerprint == nil {
+		return
+	}
+	if original, ok := c.duplicates.add(result.Link, *result.Fingerprint); ok {
+		result.NearDuplicateOf = original
+		if c.skipNearDuplicateLinks {
+			c.logger.Debug("Not following links: near-duplicate page", "url", result.Link, "original", original)
+			result.QueueLinks = nil
+		}
+	}
+}
+
 // processJob handles single page crawling
-func (c *Crawler) processJob(link crawlJob) {
-	c.limiterC <- struct{}{}
+func (c *Crawler) processJob(link Job) {
+	if !c.startJob(link) {
+		c.skipJob(link)
+		return
+	}
+	defer c.limiter.release()
 	start := time.Now()
-	log.Printf("Starting link: %s", link.Link)
-	task := newTask(link)
-	result := task.Process(c.fetcher)
+	var result crawlResult
+	if c.pageProcessor != nil {
+		result = c.pageProcessor.Process(link).crawlResult()
+	} else {
+		result = newTask(link, c.taskSettings()).Process(c.fetcher)
+	}
+	c.finishJob(link, start, result)
+}
+
+// skipJob reports the job as not processed
+func (c *Crawler) skipJob(link Job) {
+	c.logger.Debug("Link skipped: excluded or crawl stopping", "url", link.Link)
+	c.sendResult(crawlResult{Link: link.Link, Skipped: true})
+}
+
+// finishJob filters links found on the processed page and passes the result to the processor
+func (c *Crawler) finishJob(link Job, start time.Time, result crawlResult) {
+	c.mu.Lock()
+	delete(c.requests, link.Link)
+	running := c.running
+	c.mu.Unlock()
+	atomic.AddInt64(&c.inFlightN, -1)
+	if !running {
+		// The crawl has finished meanwhile, e.g. reaching max pages, nobody waits for the result
+		return
+	}
 	if result.Error != nil {
-		log.Printf("Error processing link %q: %s", link.Link, result.Error)
+		atomic.AddUint64(&c.errorsN, 1)
+		c.logger.Error("Page failed", "url", link.Link, "host", url_filter.Host(link.Link), "status", result.StatusCode,
+			"duration", time.Since(start), "error", result.Error.Error())
 	} else {
-		for i := range result.Links {
-			if cleanLink, ok := c.filter.Filter(result.Links[i].String()); ok {
-				c.queuedLinksC <- crawlJob{Link: cleanLink, Referrer: link.Referrer}
+		for i := range result.FollowLinks {
+			if cleanLink, ok := c.filter.Filter(result.FollowLinks[i].String()); ok && !c.excluded(cleanLink) {
+				result.QueueLinks = append(result.QueueLinks, cleanLink)
+			}
+		}
+		// Canonical URL is normalised the same way as links, unless it is out of scope
+		if result.CanonicalLink != "" {
+			if cleanLink, ok := c.filter.Filter(result.CanonicalLink); ok {
+				result.CanonicalLink, result.canonicalInScope = cleanLink, true
 			}
 		}
-		log.Printf("Finished link in %s: %s", time.Since(start), link.Link)
+		c.logger.Debug("Page processed", "url", link.Link, "host", url_filter.Host(link.Link), "status", result.StatusCode,
+			"duration", time.Since(start), "links", len(result.QueueLinks))
+	}
+	c.sendResult(result)
+}
+
+// sendResult passes the result to the processor unless the crawl has finished
+func (c *Crawler) sendResult(result crawlResult) {
+	select {
+	case c.processedLinksC <- result:
+	case <-c.doneC:
 	}
-	c.processedLinksC <- result
-	<-c.limiterC
-	atomic.AddUint64(&c.pagesN, 1)
+}
+
+// startJob waits for a free request slot, returns false if the job is not to be processed:
+// the crawl is stopping or the link has been excluded
+func (c *Crawler) startJob(link Job) bool {
+	started := !c.excluded(link.Link) && c.limiter.acquire()
+	atomic.AddInt64(&c.queuedN, -1)
+	// The link might have been excluded while waiting
+	if started && c.excluded(link.Link) {
+		c.limiter.release()
+		started = false
+	}
+	if started {
+		c.beginRequest(link)
+	}
+	return started
+}
+
+// beginRequest records the job as being processed
+func (c *Crawler) beginRequest(link Job) {
+	c.logger.Debug("Fetching page", "url", link.Link, "referrer", link.Referrer)
+	c.mu.Lock()
+	c.requests[link.Link] = time.Now()
+	c.mu.Unlock()
+	atomic.AddInt64(&c.inFlightN, 1)
 }
@@ -0,0 +1,48 @@
+package sqlite_batch
+
+import (
+	"database/sql"
+)
+
+// Batch groups writes into transactions, committed once every size writes
+type Batch struct {
+	db      *sql.DB
+	size    int
+	tx      *sql.Tx
+	batched int // writes done in the current transaction
+}
+
+// New creates a batch of the given size on the database
+func New(db *sql.DB, size int) *Batch {
+	return &Batch{db: db, size: size}
+}
+
+// Tx returns the current transaction, beginning a new one if there is none
+func (b *Batch) Tx() (*sql.Tx, error) {
+	if b.tx == nil {
+		tx, err := b.db.Begin()
+		if err != nil {
+			return nil, err
+		}
+		b.tx = tx
+	}
+	return b.tx, nil
+}
+
+// Done counts a write made in the current transaction, committing it once the batch is full
+func (b *Batch) Done() error {
+	if b.batched++; b.batched >= b.size {
+		return b.Commit()
+	}
+	return nil
+}
+
+// Commit commits the current transaction, if any
+func (b *Batch) Commit() error {
+	if b.tx == nil {
+		return nil
+	}
+	err := b.tx.Commit()
+	b.tx, b.batched = nil, 0
+	return err
+}
@@ -0,0 +1,43 @@
+package sqlite_batch
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestBatch(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "batch.db"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`CREATE TABLE items (n INTEGER)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	b := New(db, 2)
+	for n := 1; n <= 3; n++ {
+		tx, err := b.Tx()
+		if assert.NoError(t, err) {
+			_, err = tx.Exec(`INSERT INTO items (n) VALUES (?)`, n)
+			assert.NoError(t, err)
+			assert.NoError(t, b.Done())
+		}
+	}
+	// The first two writes are committed, the third one waits for the next batch
+	assert.NotNil(t, b.tx)
+	assert.Equal(t, 1, b.batched)
+	assert.NoError(t, b.Commit())
+	assert.Nil(t, b.tx)
+	assert.NoError(t, b.Commit(), "nothing to commit")
+	var count int
+	assert.NoError(t, db.QueryRow(`SELECT count(*) FROM items`).Scan(&count))
+	assert.Equal(t, 3, count)
+}
@@ -2,27 +2,72 @@ package crawler
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/dmitry-vovk/wcrawler/crawler/fingerprint"
 	"github.com/dmitry-vovk/wcrawler/crawler/page_fetcher"
 	"github.com/dmitry-vovk/wcrawler/crawler/page_parser"
 	"github.com/dmitry-vovk/wcrawler/crawler/types"
 )
 
-type crawlJob struct {
-	Link     string
-	Referrer string
+// Job is a page to be crawled
+type Job struct {
+	Link     string `json:"link"`
+	Referrer string `json:"referrer,omitempty"`
 }
 
 type crawlResult struct {
 	Link          string
+	StatusCode    int // Response status code, 0 if no response was received
+	Redirects     []page_fetcher.Redirect
+	FetchDuration time.Duration // Time to response headers
+	BodySize      int64         // Response body bytes read
 	CanonicalLink string
-	Links         []*url.URL
-	Error         error
+	// Canonical URL passes the filter and may be queued
+	canonicalInScope bool
+	Links            []*url.URL
+	PageLinks        []page_parser.Link // Links with attributes, URLs resolved against the page URL
+	FollowLinks      []*url.URL         // Links allowed to be followed
+	QueueLinks       []string           // Filtered and normalised links to be queued
+	NoIndex          bool
+	// Canonical URL has been seen before processing the page
+	CanonicalDuplicate bool
+	Metadata           *page_parser.PageMetadata
+	Fingerprint        *fingerprint.Fingerprint
+	Text               string
+	// Page found to be a near-duplicate of this one
+	NearDuplicateOf string
+	Error           error
+	// Job was not processed: the link has been excluded or the crawl is stopping
+	Skipped bool
+}
+
+// Result builds public page crawl result
+func (cr crawlResult) Result() Result {
+	return Result{
+		Link:               cr.Link,
+		StatusCode:         cr.StatusCode,
+		Redirects:          cr.Redirects,
+		FetchDuration:      cr.FetchDuration,
+		BodySize:           cr.BodySize,
+		CanonicalLink:      cr.CanonicalLink,
+		CanonicalDuplicate: cr.CanonicalDuplicate,
+		Links:              cr.CollectLinks(),
+		PageLinks:          cr.PageLinks,
+		NoIndex:            cr.NoIndex,
+		Metadata:           cr.Metadata,
+		Fingerprint:        cr.Fingerprint,
+		Text:               cr.Text,
+		NearDuplicateOf:    cr.NearDuplicateOf,
+		Error:              cr.Error,
+	}
 }
 
 func (cr crawlResult) CollectLinks() []string {
@@ -39,12 +84,30 @@ func (cr crawlResult) CollectLinks() []string {
 	return links
 }
 
+// taskSettings contains crawler settings affecting page processing
+type taskSettings struct {
+	parserOptions    []page_parser.Option
+	ignoreRobotsMeta bool // follow links regardless of nofollow directives
+}
+
 type task struct {
-	job crawlJob
+	job      Job
+	settings taskSettings
 }
 
-func newTask(link crawlJob) *task {
-	return &task{job: link}
+// NewRequest returns the request crawler makes to fetch the page
+func NewRequest(u *url.URL, referrer string) *page_fetcher.Request {
+	return &page_fetcher.Request{
+		URL:          u,
+		HTTPReferrer: referrer,
+		AcceptableContentTypes: map[string]struct{}{
+			"text/html": {},
+		},
+	}
+}
+
+func newTask(link Job, settings taskSettings) *task {
+	return &task{job: link, settings: settings}
 }
 
 func (t *task) Process(fetcher types.Fetcher) (result crawlResult) {
@@ -54,14 +117,9 @@ func (t *task) Process(fetcher types.Fetcher) (result crawlResult) {
 		result.Error = errors.Wrap(err, "URL parse error")
 		return
 	}
-	request := page_fetcher.Request{
-		URL:          u,
-		HTTPReferrer: t.job.Referrer,
-		AcceptableContentTypes: map[string]struct{}{
-			"text/html": {},
-		},
-	}
-	response, err := fetcher.Fetch(&request)
+	start := time.Now()
+	response, err := fetcher.Fetch(NewRequest(u, t.job.Referrer))
+	result.FetchDuration = time.Since(start)
 	if err != nil {
 		result.Error = errors.Wrap(err, "fetch")
 		return
@@ -69,20 +127,63 @@ func (t *task) Process(fetcher types.Fetcher) (result crawlResult) {
 	defer func() {
 		_ = response.Body.Close()
 	}()
+	result.StatusCode, result.Redirects = response.StatusCode, response.Redirects
 	if response.StatusCode != http.StatusOK {
 		result.Error = fmt.Errorf("got status code %d", response.StatusCode)
 		return
 	}
-	page, err := page_parser.Parse(response.Body)
+	options := append([]page_parser.Option{
+		page_parser.WithContentType(response.Headers.Get("Content-Type")),
+		page_parser.WithRobotsHeaders(response.Headers.Values("X-Robots-Tag")...),
+	}, t.settings.parserOptions...)
+	body := countingReader{r: response.Body}
+	page, err := page_parser.Parse(&body, options...)
+	result.BodySize = body.n
 	if err != nil {
 		result.Error = errors.Wrap(err, "parse")
 		return
 	}
 	for i := range page.Links {
-		if pageLink, err := url.Parse(page.Links[i]); err == nil {
-			result.Links = append(result.Links, u.ResolveReference(pageLink))
+		if pageLink, err := url.Parse(page.Links[i].URL); err == nil {
+			link := u.ResolveReference(pageLink)
+			result.Links = append(result.Links, link)
+			detailed := page.Links[i]
+			detailed.URL = link.String()
+			result.PageLinks = append(result.PageLinks, detailed)
+			if t.settings.ignoreRobotsMeta || !(page.Robots.NoFollow || page.Links[i].NoFollow()) {
+				result.FollowLinks = append(result.FollowLinks, link)
+			}
+		}
+	}
+	for i := range page.Metadata.Alternates {
+		if alternate, err := url.Parse(page.Metadata.Alternates[i].URL); err == nil {
+			page.Metadata.Alternates[i].URL = u.ResolveReference(alternate).String()
+		}
+	}
+	result.NoIndex = page.Robots.NoIndex
+	result.Metadata = &page.Metadata
+	// Pages without visible text, e.g. redirects or framesets, are not duplicates of each other
+	if strings.TrimSpace(page.Text) != "" {
+		fp := fingerprint.Compute(page.Text, fingerprint.DefaultShingleSize)
+		result.Fingerprint = &fp
+	}
+	result.Text = page.Text
+	if page.CanonicalURL != "" {
+		if canonical, err := url.Parse(page.CanonicalURL); err == nil {
+			result.CanonicalLink = u.ResolveReference(canonical).String()
 		}
 	}
-	result.CanonicalLink = page.CanonicalURL
 	return
 }
+
+// countingReader counts bytes read
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -1,14 +1,83 @@
 package crawler
 
 import (
+	"net/http"
 	"testing"
 
+	"github.com/dmitry-vovk/wcrawler/crawler/page_parser"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestTask(t *testing.T) {
-	task := newTask(crawlJob{
+	task := newTask(Job{
 		Link: string(rune(0x7f)),
-	})
+	}, taskSettings{})
 	assert.Error(t, task.Process(nil).Error)
 }
+
+func TestTask_RobotsMeta(t *testing.T) {
+	const html = `<html><head><meta name="crawlbot" content="noindex"></head><body>
+		<a href="/a">A</a>
+		<a href="/b" rel="nofollow">B</a>
+	</body></html>`
+	testCases := []struct {
+		name     string
+		headers  http.Header
+		settings taskSettings
+		follow   []string
+		noIndex  bool
+	}{
+		{
+			name:    "nofollow link",
+			follow:  []string{"http://example.com/a"},
+			noIndex: false,
+		},
+		{
+			name:     "agent-specific meta",
+			settings: taskSettings{parserOptions: []page_parser.Option{page_parser.WithUserAgent("CrawlBot/1.0")}},
+			follow:   []string{"http://example.com/a"},
+			noIndex:  true,
+		},
+		{
+			name:    "nofollow header",
+			headers: http.Header{"X-Robots-Tag": {"nofollow"}},
+			follow:  nil,
+		},
+		{
+			name:     "ignored directives",
+			headers:  http.Header{"X-Robots-Tag": {"nofollow"}},
+			settings: taskSettings{ignoreRobotsMeta: true},
+			follow:   []string{"http://example.com/a", "http://example.com/b"},
+		},
+	}
+	for _, tt := range testCases {
+		result := newTask(Job{Link: "http://example.com/"}, tt.settings).Process(&testFetcher{
+			statusCode: 200,
+			html:       html,
+			headers:    tt.headers,
+		})
+		if assert.NoError(t, result.Error, tt.name) {
+			assert.Len(t, result.Links, 2, tt.name)
+			var follow []string
+			for _, link := range result.FollowLinks {
+				follow = append(follow, link.String())
+			}
+			assert.Equal(t, tt.follow, follow, tt.name)
+			assert.Equal(t, tt.noIndex, result.NoIndex, tt.name)
+		}
+	}
+}
+
+func TestTask_Alternates(t *testing.T) {
+	const html = `<html><head>
+		<link rel="alternate" hreflang="de" href="/de/">
+		<link rel="alternate" hreflang="fr" href="https://example.fr/">
+	</head></html>`
+	result := newTask(Job{Link: "http://example.com/en/"}, taskSettings{}).Process(&testFetcher{statusCode: 200, html: html})
+	if assert.NoError(t, result.Error) && assert.NotNil(t, result.Metadata) {
+		assert.Equal(t, []page_parser.Alternate{
+			{HrefLang: "de", URL: "http://example.com/de/"},
+			{HrefLang: "fr", URL: "https://example.fr/"},
+		}, result.Metadata.Alternates)
+	}
+}
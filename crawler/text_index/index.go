@@ -0,0 +1,321 @@
+package text_index
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"os"
+	"sort"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/sqlite_batch"
+	_ "modernc.org/sqlite" // registers "sqlite" database driver
+)
+
+// How many documents are added in a single transaction, written to the file on commit
+const defaultBatchSize = 100
+
+const indexSchema = `
+CREATE TABLE IF NOT EXISTS documents (
+	id    INTEGER PRIMARY KEY,
+	url   TEXT NOT NULL,
+	title TEXT NOT NULL,
+	words INTEGER NOT NULL -- number of words in the text
+);
+CREATE TABLE IF NOT EXISTS postings (
+	term      TEXT NOT NULL,
+	doc       INTEGER NOT NULL REFERENCES documents (id),
+	positions BLOB NOT NULL, -- uvarint deltas of word positions
+	PRIMARY KEY (term, doc)
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS passages (
+	doc   INTEGER NOT NULL REFERENCES documents (id),
+	start INTEGER NOT NULL, -- position of the first word
+	text  TEXT NOT NULL,    -- words around the first occurrences of terms, for snippets
+	PRIMARY KEY (doc, start)
+) WITHOUT ROWID;
+`
+
+// Index is an inverted full-text index of crawled pages kept in SQLite database file,
+// documents are written as they are added so memory use stays flat. Instead of the whole text
+// only passages around the first occurrence of every term are kept, enough for snippets of any match
+type Index struct {
+	db    *sql.DB
+	batch *sqlite_batch.Batch
+	docs  int // documents added
+}
+
+// Posting lists term positions within a document
+type Posting struct {
+	Doc       int
+	Positions []int
+}
+
+// Match is a search result
+type Match struct {
+	URL     string
+	Title   string
+	Snippet string
+}
+
+// Create creates the index file, removing documents of an existing one
+func Create(filePath string) (*Index, error) {
+	db, err := sql.Open("sqlite", filePath)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	// Tables of an older layout are replaced
+	if _, err := db.Exec(`DROP TABLE IF EXISTS passages; DROP TABLE IF EXISTS postings; DROP TABLE IF EXISTS documents;` +
+		indexSchema); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Index{db: db, batch: sqlite_batch.New(db, defaultBatchSize)}, nil
+}
+
+// Open opens existing index file for searching
+func Open(filePath string) (*Index, error) {
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", filePath)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return &Index{db: db, batch: sqlite_batch.New(db, defaultBatchSize)}, nil
+}
+
+// Add indexes page text
+func (idx *Index) Add(url, title, text string) error {
+	tx, err := idx.batch.Tx()
+	if err != nil {
+		return err
+	}
+	tokens := tokenize(text)
+	res, err := tx.Exec(`INSERT INTO documents (url, title, words) VALUES (?, ?, ?)`, url, title, len(tokens))
+	if err != nil {
+		return err
+	}
+	doc, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	positions := make(map[string][]int)
+	for i := range tokens {
+		positions[tokens[i].term] = append(positions[tokens[i].term], i)
+	}
+	for term, pos := range positions {
+		if _, err := tx.Exec(`INSERT INTO postings (term, doc, positions) VALUES (?, ?, ?)`,
+			term, doc, encodePositions(pos)); err != nil {
+			return err
+		}
+	}
+	for _, w := range passageWindows(tokens) {
+		if _, err := tx.Exec(`INSERT INTO passages (doc, start, text) VALUES (?, ?, ?)`,
+			doc, w.from, text[tokens[w.from].start:tokens[w.to].end]); err != nil {
+			return err
+		}
+	}
+	idx.docs++
+	return idx.batch.Done()
+}
+
+// Len returns the number of documents added since the index has been created or opened
+func (idx *Index) Len() int {
+	return idx.docs
+}
+
+// Close writes pending documents and closes the file
+func (idx *Index) Close() error {
+	err := idx.batch.Commit()
+	if closeErr := idx.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Search returns documents matching the query, in the order of indexing
+func (idx *Index) Search(query string) ([]Match, error) {
+	q, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := idx.batch.Commit(); err != nil {
+		return nil, err
+	}
+	docs, err := q.eval(idx)
+	if err != nil {
+		return nil, err
+	}
+	highlight := make(map[string]struct{})
+	q.terms(highlight)
+	matches := make([]Match, 0, len(docs))
+	for _, doc := range docs.sorted() {
+		var m Match
+		var words int
+		if err := idx.db.QueryRow(`SELECT url, title, words FROM documents WHERE id = ?`, doc).
+			Scan(&m.URL, &m.Title, &words); err != nil {
+			return nil, err
+		}
+		if m.Snippet, err = idx.snippet(doc, words, highlight); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// snippet returns document text around the first occurrence of any of the terms, or the text start
+func (idx *Index) snippet(doc, words int, highlight map[string]struct{}) (string, error) {
+	match := -1
+	for term := range highlight {
+		var positions []byte
+		err := idx.db.QueryRow(`SELECT positions FROM postings WHERE term = ? AND doc = ?`, term, doc).Scan(&positions)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		if pos := decodePositions(positions); len(pos) > 0 && (match < 0 || pos[0] < match) {
+			match = pos[0]
+		}
+	}
+	match = max(match, 0)
+	var start int
+	var passage string
+	err := idx.db.QueryRow(`SELECT start, text FROM passages WHERE doc = ? AND start <= ? ORDER BY start DESC LIMIT 1`,
+		doc, match).Scan(&start, &passage)
+	if errors.Is(err, sql.ErrNoRows) {
+		// No words in the text
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return snippet(passage, start, match, words), nil
+}
+
+// docSet is a set of document numbers
+type docSet map[int]struct{}
+
+func (s docSet) sorted() []int {
+	docs := make([]int, 0, len(s))
+	for doc := range s {
+		docs = append(docs, doc)
+	}
+	sort.Ints(docs)
+	return docs
+}
+
+// all returns all indexed documents
+func (idx *Index) all() (docSet, error) {
+	rows, err := idx.db.Query(`SELECT id FROM documents`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	docs := make(docSet)
+	for rows.Next() {
+		var doc int
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		docs[doc] = struct{}{}
+	}
+	return docs, rows.Err()
+}
+
+// postings returns documents containing the term, ordered by document
+func (idx *Index) postings(term string) ([]Posting, error) {
+	rows, err := idx.db.Query(`SELECT doc, positions FROM postings WHERE term = ? ORDER BY doc`, term)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var postings []Posting
+	for rows.Next() {
+		var p Posting
+		var positions []byte
+		if err := rows.Scan(&p.Doc, &positions); err != nil {
+			return nil, err
+		}
+		p.Positions = decodePositions(positions)
+		postings = append(postings, p)
+	}
+	return postings, rows.Err()
+}
+
+// phrase returns documents containing the terms in sequence
+func (idx *Index) phrase(words []string) (docSet, error) {
+	docs := make(docSet)
+	if len(words) == 0 {
+		return docs, nil
+	}
+	// Positions of every phrase word by document
+	positions := make([]map[int]map[int]struct{}, len(words))
+	var first []Posting
+	for i, word := range words {
+		postings, err := idx.postings(word)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			first = postings
+		}
+		positions[i] = make(map[int]map[int]struct{})
+		for _, p := range postings {
+			set := make(map[int]struct{}, len(p.Positions))
+			for _, pos := range p.Positions {
+				set[pos] = struct{}{}
+			}
+			positions[i][p.Doc] = set
+		}
+	}
+next:
+	for _, p := range first {
+		for _, start := range p.Positions {
+			found := true
+			for i := 1; i < len(words); i++ {
+				if _, ok := positions[i][p.Doc][start+i]; !ok {
+					found = false
+					break
+				}
+			}
+			if found {
+				docs[p.Doc] = struct{}{}
+				continue next
+			}
+		}
+	}
+	return docs, nil
+}
+
+// encodePositions packs ascending positions as deltas
+func encodePositions(positions []int) []byte {
+	buf := make([]byte, 0, len(positions))
+	prev := 0
+	for _, pos := range positions {
+		buf = binary.AppendUvarint(buf, uint64(pos-prev))
+		prev = pos
+	}
+	return buf
+}
+
+func decodePositions(buf []byte) []int {
+	var positions []int
+	prev := 0
+	for len(buf) > 0 {
+		delta, n := binary.Uvarint(buf)
+		if n <= 0 {
+			break
+		}
+		prev += int(delta)
+		positions = append(positions, prev)
+		buf = buf[n:]
+	}
+	return positions
+}
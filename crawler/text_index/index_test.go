@@ -0,0 +1,152 @@
+package text_index
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/sqlite_batch"
+)
+
+// testIndex creates index of a few pages, closed once the test ends
+func testIndex(t *testing.T, pages ...[3]string) *Index {
+	idx, err := Create(filepath.Join(t.TempDir(), "index.db"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() {
+		_ = idx.Close()
+	})
+	if len(pages) == 0 {
+		pages = [][3]string{
+			{"http://example.com/", "Home", "Welcome to our shop. We sell red shoes and blue hats."},
+			{"http://example.com/shoes", "Shoes", "Red shoes, green shoes and shoes for running."},
+			{"http://example.com/hats", "Hats", "Blue hats are back in stock. Shoes are not."},
+			{"http://example.com/about", "About", "Über uns: wir verkaufen Schuhe seit 1999."},
+		}
+	}
+	for _, page := range pages {
+		assert.NoError(t, idx.Add(page[0], page[1], page[2]))
+	}
+	return idx
+}
+
+func TestSearch(t *testing.T) {
+	idx := testIndex(t)
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "shoes", expected: []string{"http://example.com/", "http://example.com/shoes", "http://example.com/hats"}},
+		{query: "SHOES hats", expected: []string{"http://example.com/", "http://example.com/hats"}},
+		{query: "shoes AND hats", expected: []string{"http://example.com/", "http://example.com/hats"}},
+		{query: `"red shoes"`, expected: []string{"http://example.com/", "http://example.com/shoes"}},
+		{query: `"shoes red"`, expected: []string{}},
+		{query: "running OR stock", expected: []string{"http://example.com/shoes", "http://example.com/hats"}},
+		{query: "shoes -hats", expected: []string{"http://example.com/shoes"}},
+		{query: "shoes NOT (hats OR running)", expected: []string{}},
+		{query: "NOT shoes", expected: []string{"http://example.com/about"}},
+		{query: "über", expected: []string{"http://example.com/about"}},
+		{query: "1999", expected: []string{"http://example.com/about"}},
+		{query: "missing", expected: []string{}},
+	}
+	for _, tt := range testCases {
+		matches, err := idx.Search(tt.query)
+		if assert.NoError(t, err, tt.query) {
+			urls := make([]string, 0, len(matches))
+			for _, m := range matches {
+				urls = append(urls, m.URL)
+			}
+			assert.Equal(t, tt.expected, urls, tt.query)
+		}
+	}
+}
+
+func TestSearch_BadQuery(t *testing.T) {
+	idx := testIndex(t)
+	for _, query := range []string{"", "   ", "(shoes", "shoes)", "shoes OR", "AND shoes", "NOT", `"..."`} {
+		_, err := idx.Search(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestSearch_Snippet(t *testing.T) {
+	idx := testIndex(t,
+		[3]string{"/", "", "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty"},
+		[3]string{"/short", "Short", "Hello,\n\tworld!"},
+		[3]string{"/long", "Long", "start " + strings.Repeat("filler ", 1000) + "needle in a haystack " + strings.Repeat("filler ", 1000) + "end"},
+	)
+	if matches, err := idx.Search("fifteen"); assert.NoError(t, err) && assert.Len(t, matches, 1) {
+		assert.Equal(t, "…seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty", matches[0].Snippet)
+	}
+	if matches, err := idx.Search("two"); assert.NoError(t, err) && assert.Len(t, matches, 1) {
+		assert.Equal(t, "one two three four five six seven eight nine ten…", matches[0].Snippet)
+	}
+	if matches, err := idx.Search("-two -start"); assert.NoError(t, err) && assert.Len(t, matches, 1) {
+		assert.Equal(t, Match{URL: "/short", Title: "Short", Snippet: "Hello, world"}, matches[0])
+	}
+	// Matches far into the text are shown in their context
+	if matches, err := idx.Search("haystack"); assert.NoError(t, err) && assert.Len(t, matches, 1) {
+		assert.Equal(t, "…filler filler filler filler filler needle in a haystack filler filler filler filler filler filler filler filler…", matches[0].Snippet)
+	}
+	if matches, err := idx.Search("end OR needle"); assert.NoError(t, err) && assert.Len(t, matches, 1) {
+		assert.Equal(t, "…filler filler filler filler filler filler filler filler needle in a haystack filler filler filler filler filler…", matches[0].Snippet,
+			"the first match is shown")
+	}
+	if matches, err := idx.Search("end"); assert.NoError(t, err) && assert.Len(t, matches, 1) {
+		assert.Equal(t, "…filler filler filler filler filler filler filler filler end", matches[0].Snippet)
+	}
+}
+
+func TestPassageWindows(t *testing.T) {
+	tokens := tokenize("a " + strings.Repeat("x ", 30) + "b c " + strings.Repeat("x ", 5) + "d")
+	assert.Equal(t, []window{{from: 0, to: 9}, {from: 23, to: 38}}, passageWindows(tokens))
+	assert.Empty(t, passageWindows(nil))
+}
+
+func TestCreateOpen(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "index.db")
+	idx, err := Create(filePath)
+	if assert.NoError(t, err) {
+		idx.batch = sqlite_batch.New(idx.db, 2)
+		assert.NoError(t, idx.Add("/a", "A", "red shoes"))
+		assert.NoError(t, idx.Add("/b", "B", "blue shoes"))
+		assert.NoError(t, idx.Add("/c", "C", "red hats"))
+		assert.Equal(t, 3, idx.Len())
+		// Committed batches are readable before the index is closed
+		if reader, err := Open(filePath); assert.NoError(t, err) {
+			matches, err := reader.Search("shoes")
+			assert.NoError(t, err)
+			assert.Len(t, matches, 2)
+			assert.NoError(t, reader.Close())
+		}
+		assert.NoError(t, idx.Close())
+	}
+	idx, err = Open(filePath)
+	if assert.NoError(t, err) {
+		matches, err := idx.Search("red")
+		if assert.NoError(t, err) {
+			assert.Equal(t, []Match{{URL: "/a", Title: "A", Snippet: "red shoes"}, {URL: "/c", Title: "C", Snippet: "red hats"}}, matches)
+		}
+		assert.NoError(t, idx.Close())
+	}
+	// Re-created index starts empty
+	idx, err = Create(filePath)
+	if assert.NoError(t, err) {
+		matches, err := idx.Search("red")
+		assert.NoError(t, err)
+		assert.Empty(t, matches)
+		assert.NoError(t, idx.Close())
+	}
+	_, err = Open(filepath.Join(t.TempDir(), "missing.db"))
+	assert.Error(t, err)
+	_, err = Create(filepath.Join(t.TempDir(), "missing", "index.db"))
+	assert.Error(t, err)
+}
+
+func TestPositions(t *testing.T) {
+	positions := []int{0, 1, 5, 300, 70000}
+	assert.Equal(t, positions, decodePositions(encodePositions(positions)))
+}
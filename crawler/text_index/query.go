@@ -0,0 +1,246 @@
+package text_index
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrEmptyQuery = errors.New("empty query")
+
+// queryNode is a node of parsed query tree
+type queryNode interface {
+	eval(idx *Index) (docSet, error)
+	terms(highlight map[string]struct{})
+}
+
+type (
+	// phraseNode matches a word or a sequence of words
+	phraseNode struct{ words []string }
+	// andNode matches documents matching all the nodes
+	andNode struct{ nodes []queryNode }
+	// orNode matches documents matching any of the nodes
+	orNode struct{ nodes []queryNode }
+	// notNode matches documents not matching the node
+	notNode struct{ node queryNode }
+)
+
+func (n phraseNode) eval(idx *Index) (docSet, error) {
+	return idx.phrase(n.words)
+}
+
+func (n phraseNode) terms(highlight map[string]struct{}) {
+	for _, word := range n.words {
+		highlight[word] = struct{}{}
+	}
+}
+
+func (n andNode) eval(idx *Index) (docSet, error) {
+	docs, err := n.nodes[0].eval(idx)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range n.nodes[1:] {
+		other, err := node.eval(idx)
+		if err != nil {
+			return nil, err
+		}
+		for doc := range docs {
+			if _, ok := other[doc]; !ok {
+				delete(docs, doc)
+			}
+		}
+	}
+	return docs, nil
+}
+
+func (n andNode) terms(highlight map[string]struct{}) {
+	for _, node := range n.nodes {
+		node.terms(highlight)
+	}
+}
+
+func (n orNode) eval(idx *Index) (docSet, error) {
+	docs := make(docSet)
+	for _, node := range n.nodes {
+		other, err := node.eval(idx)
+		if err != nil {
+			return nil, err
+		}
+		for doc := range other {
+			docs[doc] = struct{}{}
+		}
+	}
+	return docs, nil
+}
+
+func (n orNode) terms(highlight map[string]struct{}) {
+	for _, node := range n.nodes {
+		node.terms(highlight)
+	}
+}
+
+func (n notNode) eval(idx *Index) (docSet, error) {
+	docs, err := idx.all()
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := n.node.eval(idx)
+	if err != nil {
+		return nil, err
+	}
+	for doc := range excluded {
+		delete(docs, doc)
+	}
+	return docs, nil
+}
+
+func (n notNode) terms(map[string]struct{}) {}
+
+// queryParser parses queries of the following grammar:
+//
+//	query  = or
+//	or     = and { "OR" and }
+//	and    = unary { [ "AND" ] unary }
+//	unary  = ( "NOT" | "-" ) unary | "(" or ")" | phrase | word
+//	phrase = '"' words '"'
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+// parseQuery builds query tree out of the query string
+func parseQuery(query string) (queryNode, error) {
+	p := queryParser{tokens: splitQuery(query)}
+	if len(p.tokens) == 0 {
+		return nil, ErrEmptyQuery
+	}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return node, nil
+}
+
+// splitQuery splits the query into words, quoted phrases, parentheses and minus signs
+func splitQuery(query string) []string {
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	inPhrase := false
+	for _, r := range query {
+		switch {
+		case r == '"' && !inPhrase:
+			flush()
+			current.WriteRune(r)
+			inPhrase = true
+		case r == '"':
+			current.WriteRune(r)
+			flush()
+			inPhrase = false
+		case inPhrase:
+			current.WriteRune(r)
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case r == '-' && current.Len() == 0:
+			tokens = append(tokens, "-")
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func (p *queryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *queryParser) or() (queryNode, error) {
+	node, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []queryNode{node}
+	for p.peek() == "OR" {
+		p.pos++
+		if node, err = p.and(); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return orNode{nodes: nodes}, nil
+}
+
+func (p *queryParser) and() (queryNode, error) {
+	node, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []queryNode{node}
+	for {
+		switch p.peek() {
+		case "", "OR", ")":
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return andNode{nodes: nodes}, nil
+		case "AND":
+			p.pos++
+		}
+		if node, err = p.unary(); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+func (p *queryParser) unary() (queryNode, error) {
+	token := p.peek()
+	p.pos++
+	switch {
+	case token == "":
+		return nil, errors.New("unexpected end of query")
+	case token == "NOT" || token == "-":
+		node, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node: node}, nil
+	case token == "(":
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	case token == ")" || token == "AND" || token == "OR":
+		return nil, fmt.Errorf("unexpected %q", token)
+	default:
+		words := terms(strings.Trim(token, `"`))
+		if len(words) == 0 {
+			return nil, fmt.Errorf("no words in %q", token)
+		}
+		return phraseNode{words: words}, nil
+	}
+}
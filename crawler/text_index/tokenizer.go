@@ -0,0 +1,95 @@
+package text_index
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// token is a single normalised word with its location in the source text
+type token struct {
+	term  string
+	start int // byte offset of the word start
+	end   int // byte offset after the word end
+}
+
+// tokenize splits text into lowercased words made of letters and digits
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if word && start < 0 {
+			start = i
+		} else if !word && start >= 0 {
+			tokens = append(tokens, token{term: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{term: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// terms returns normalised words of the text
+func terms(text string) []string {
+	tokens := tokenize(text)
+	result := make([]string, len(tokens))
+	for i := range tokens {
+		result[i] = tokens[i].term
+	}
+	return result
+}
+
+// Number of words to show around the match in snippets
+const snippetWords = 8
+
+// window is a range of word positions, both ends included
+type window struct {
+	from, to int
+}
+
+// passageWindows returns merged windows of snippetWords around the first occurrence of every term,
+// so a snippet can be cut around any match without keeping the whole text
+func passageWindows(tokens []token) []window {
+	var windows []window
+	seen := make(map[string]struct{})
+	for i := range tokens {
+		if _, ok := seen[tokens[i].term]; ok {
+			continue
+		}
+		seen[tokens[i].term] = struct{}{}
+		w := window{from: max(i-snippetWords, 0), to: min(i+snippetWords, len(tokens)-1)}
+		if n := len(windows); n > 0 && w.from <= windows[n-1].to+1 {
+			windows[n-1].to = w.to
+		} else {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// snippet returns fragment of the passage around the word at match position;
+// passage starts with the word at start position, the text has the given number of words
+func snippet(passage string, start, match, words int) string {
+	tokens := tokenize(passage)
+	if len(tokens) == 0 {
+		return ""
+	}
+	match -= start
+	from, to := max(match-snippetWords, 0), min(match+snippetWords, len(tokens)-1)
+	fragment := passage[tokens[from].start:tokens[to].end]
+	if start+from > 0 {
+		fragment = "…" + fragment
+	}
+	if start+to < words-1 {
+		fragment += "…"
+	}
+	// Make sure the fragment does not break lines in the output
+	fragment = strings.Join(strings.Fields(fragment), " ")
+	if !utf8.ValidString(fragment) {
+		return strings.ToValidUTF8(fragment, "")
+	}
+	return fragment
+}
@@ -12,3 +12,9 @@ type Fetcher interface {
 type Filter interface {
 	Filter(link string) (string, bool)
 }
+
+// VisitedSet remembers links seen by the crawler, used from a single goroutine
+type VisitedSet interface {
+	// Add marks the link as seen, returns false if it has been seen already
+	Add(link string) (bool, error)
+}
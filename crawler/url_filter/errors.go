@@ -0,0 +1,27 @@
+package url_filter
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrBadURL           = errors.New("unparseable URL")
+	ErrOutOfScope       = errors.New("host out of scope")
+	ErrSchemeNotAllowed = errors.New("scheme not allowed")
+	ErrPortNotAllowed   = errors.New("port not allowed")
+	ErrRobotsDisallowed = errors.New("disallowed by robots.txt")
+	ErrNotIncluded      = errors.New("not matched by any include rule")
+)
+
+// RuleError tells which rule rejected the link
+type RuleError struct {
+	// Number of the rule in the list, starting with 1
+	N int
+	// Rule that rejected the link
+	Rule Rule
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("excluded by rule #%d: %s", e.N, e.Rule)
+}
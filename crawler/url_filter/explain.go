@@ -0,0 +1,55 @@
+package url_filter
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Step is a single decision of the filter chain
+type Step struct {
+	// Name of the step, e.g. "scope", "rules", "robots.txt" or a normalisation step
+	Name string
+	// What the step did or found, the link after normalisation steps
+	Detail string
+	// Rejection reason, nil if the link passed the step
+	Err error
+}
+
+func (s Step) String() string {
+	if s.Err != nil {
+		return fmt.Sprintf("%s: rejected, %s (%s)", s.Name, s.Err, s.Detail)
+	}
+	return s.Name + ": " + s.Detail
+}
+
+// Explain runs the link through the filter chain recording every step,
+// returns normalised link or the reason the link is rejected, same as Check
+func (f *NormalizingFilter) Explain(link string) ([]Step, string, error) {
+	var steps []Step
+	normal, err := f.check(link, func(step Step) {
+		steps = append(steps, step)
+	})
+	return steps, normal, err
+}
+
+// rulesDecision describes which include/exclude rule let the URL through
+func (f *NormalizingFilter) rulesDecision(u *url.URL) string {
+	if len(f.rules) == 0 {
+		return "no rules"
+	}
+	if i := firstMatch(f.rules, u); i >= 0 {
+		return fmt.Sprintf("matched rule #%d: %s", i+1, f.rules[i])
+	}
+	return "no rule matched"
+}
+
+// robotsDecision describes robots.txt decision on the URL
+func (f *NormalizingFilter) robotsDecision(u *url.URL) string {
+	if r, ok := f.robots.(RobotsExplainer); ok {
+		return r.ExplainURL(u, f.userAgent)
+	}
+	if f.robotsAllowed(u) {
+		return fmt.Sprintf("%s allowed for %q", u.Path, f.userAgent)
+	}
+	return fmt.Sprintf("%s disallowed for %q", u.Path, f.userAgent)
+}
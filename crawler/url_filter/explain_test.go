@@ -0,0 +1,72 @@
+package url_filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	f := NewFilter("example.com").
+		WithRules(Rule{Action: Exclude, Matcher: PathPrefix("/private")}).
+		WithNormalisation(Normalisation{StripParams: []string{"utm_*"}, LowercasePath: true}).
+		WithRobots(&testRobot{}, "Bot")
+	testCases := []struct {
+		link     string
+		expected []string
+		normal   string
+		err      string
+	}{
+		{
+			link: "http://example.com/Docs/?utm_source=x",
+			expected: []string{
+				"scope: http://example.com is in scope",
+				"strip parameters: http://example.com/Docs/",
+				"lowercase path: http://example.com/docs/",
+				"normalise: http://example.com/docs",
+				"rules: no rule matched",
+				`robots.txt: /docs allowed for "Bot"`,
+			},
+			normal: "http://example.com/docs",
+		},
+		{
+			link: "http://example.com/Private",
+			expected: []string{
+				"scope: http://example.com is in scope",
+				"lowercase path: http://example.com/private",
+				"rules: rejected, excluded by rule #1: exclude path prefix /private (http://example.com/private)",
+			},
+			err: "excluded by rule #1: exclude path prefix /private",
+		},
+		{
+			link: "http://example.com/fail",
+			expected: []string{
+				"scope: http://example.com is in scope",
+				"rules: no rule matched",
+				`robots.txt: rejected, disallowed by robots.txt (/fail disallowed for "Bot")`,
+			},
+			err: ErrRobotsDisallowed.Error(),
+		},
+		{
+			link: "http://other.com/",
+			expected: []string{
+				"scope: rejected, host out of scope (http://other.com/)",
+			},
+			err: ErrOutOfScope.Error(),
+		},
+	}
+	for _, tt := range testCases {
+		steps, normal, err := f.Explain(tt.link)
+		var trace []string
+		for i := range steps {
+			trace = append(trace, steps[i].String())
+		}
+		assert.Equal(t, tt.expected, trace, tt.link)
+		assert.Equal(t, tt.normal, normal, tt.link)
+		if tt.err == "" {
+			assert.NoError(t, err, tt.link)
+		} else if assert.Error(t, err, tt.link) {
+			assert.Equal(t, tt.err, err.Error(), tt.link)
+		}
+	}
+}
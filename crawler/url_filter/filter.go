@@ -1,9 +1,9 @@
 package url_filter
 
 import (
-	"log"
+	"errors"
+	"log/slog"
 	"net/url"
-	"strings"
 
 	"github.com/PuerkitoBio/purell"
 )
@@ -13,12 +13,25 @@ type RobotsChecker interface {
 	TestAgent(path, agent string) bool
 }
 
+// URLRobotsChecker is a RobotsChecker aware of hosts, used instead of TestAgent when implemented
+type URLRobotsChecker interface {
+	TestURL(u *url.URL, agent string) bool
+}
+
+// RobotsExplainer is a RobotsChecker able to tell which robots.txt rule decides on the URL
+type RobotsExplainer interface {
+	ExplainURL(u *url.URL, agent string) string
+}
+
 // NormalizingFilter is a URL filter with basic normalisation rules
 type NormalizingFilter struct {
-	baseDomain string
-	allowWWW   bool
-	robots     RobotsChecker
-	userAgent  string
+	scope     Scope
+	robots    RobotsChecker
+	userAgent string
+	rules     []Rule
+	normalise Normalisation
+	logger    *slog.Logger
+	onReject  func(link string, err error) // called for every rejected link
 }
 
 var (
@@ -31,14 +44,25 @@ var (
 // NewFilter returns and instance of NormalizingFilter with sane defaults
 func NewFilter(baseDomain string) *NormalizingFilter {
 	f := NormalizingFilter{
-		baseDomain: baseDomain,
+		scope:  Scope{BaseDomain: baseDomain, Hosts: HostExact},
+		logger: slog.Default(),
 	}
 	return &f
 }
 
 // AllowWWWPrefix sets switch for handling commonly used "www" prefix
 func (f *NormalizingFilter) AllowWWWPrefix(allow bool) *NormalizingFilter {
-	f.allowWWW = allow
+	if allow {
+		f.scope.Hosts = HostWWW
+	} else {
+		f.scope.Hosts = HostExact
+	}
+	return f
+}
+
+// WithScope replaces crawl scope, including base domain
+func (f *NormalizingFilter) WithScope(scope Scope) *NormalizingFilter {
+	f.scope = scope
 	return f
 }
 
@@ -48,34 +72,128 @@ func (f *NormalizingFilter) WithRobots(r RobotsChecker, agent string) *Normalizi
 	return f
 }
 
+// WithRules sets ordered include/exclude rules, the first matching rule decides
+func (f *NormalizingFilter) WithRules(rules ...Rule) *NormalizingFilter {
+	f.rules = rules
+	return f
+}
+
+// WithNormalisation sets link normalisation policy
+func (f *NormalizingFilter) WithNormalisation(n Normalisation) *NormalizingFilter {
+	f.normalise = n
+	return f
+}
+
+// WithLogger sets logger, robots.txt denials are logged as warnings, other rejections at debug level
+func (f *NormalizingFilter) WithLogger(logger *slog.Logger) *NormalizingFilter {
+	f.logger = logger
+	return f
+}
+
+// OnReject sets function called with every link rejected by Filter and the reason, e.g. to count rejections
+func (f *NormalizingFilter) OnReject(fn func(link string, err error)) *NormalizingFilter {
+	f.onReject = fn
+	return f
+}
+
 // Filter returns normalized link and/or tells if the link is ok to use
 func (f *NormalizingFilter) Filter(link string) (string, bool) {
-	u, err := url.Parse(link)
+	normal, err := f.Check(link)
 	if err != nil {
+		if errors.Is(err, ErrRobotsDisallowed) {
+			f.logger.Warn("Link disallowed by robots.txt", "url", link, "host", Host(link))
+		} else {
+			f.logger.Debug("Link rejected", "url", link, "reason", err)
+		}
+		if f.onReject != nil {
+			f.onReject(link, err)
+		}
 		return "", false
 	}
+	return normal, true
+}
+
+// Check returns normalized link or the reason the link is rejected
+func (f *NormalizingFilter) Check(link string) (string, error) {
+	return f.check(link, nil)
+}
+
+// check runs the link through the filter chain, trace is called for every step if not nil
+func (f *NormalizingFilter) check(link string, trace func(Step)) (string, error) {
+	note := func(step Step) {
+		if trace != nil {
+			trace(step)
+		}
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		note(Step{Name: "parse", Detail: err.Error(), Err: ErrBadURL})
+		return "", ErrBadURL
+	}
 	if u.Path == "" {
 		u.Path = "/"
 	}
-	if f.baseDomain != "" {
-		host := u.Hostname()
-		if f.allowWWW {
-			if strings.TrimPrefix(host, "www.") != strings.TrimPrefix(f.baseDomain, "www.") {
-				return "", false
-			}
-		} else if host != f.baseDomain {
-			return "", false
+	if err := f.scope.check(u); err != nil {
+		note(Step{Name: "scope", Detail: u.String(), Err: err})
+		return "", err
+	}
+	note(Step{Name: "scope", Detail: u.Scheme + "://" + u.Host + " is in scope"})
+	var normaliseTrace func(step, link string)
+	if trace != nil {
+		normaliseTrace = func(step, link string) {
+			trace(Step{Name: step, Detail: link})
+		}
+	}
+	original := *u
+	link = f.normalise.normalise(u, normaliseTrace)
+	// Normalised link is always parseable
+	u, _ = url.Parse(link)
+	if err := applyRules(f.rules, u); err != nil {
+		note(Step{Name: "rules", Detail: link, Err: err})
+		return "", err
+	}
+	if trace != nil {
+		note(Step{Name: "rules", Detail: f.rulesDecision(u)})
+	}
+	if f.robots == nil {
+		note(Step{Name: "robots.txt", Detail: "not used"})
+		return link, nil
+	}
+	robotsURL := u
+	allowed := f.robotsAllowed(u)
+	if allowed && f.normalise.LowercasePath {
+		// robots.txt paths are case-sensitive, the path as linked must be allowed too
+		n := f.normalise
+		n.LowercasePath = false
+		if cased, err := url.Parse(n.normalise(&original, nil)); err == nil && cased.Path != u.Path && !f.robotsAllowed(cased) {
+			robotsURL, allowed = cased, false
 		}
 	}
-	if f.robots != nil {
-		if !f.robots.TestAgent(u.Path, f.userAgent) {
-			log.Printf("Visiting %s disallowed by robots.txt", link)
-			return "", false
+	if trace != nil {
+		step := Step{Name: "robots.txt", Detail: f.robotsDecision(robotsURL)}
+		if !allowed {
+			step.Err = ErrRobotsDisallowed
 		}
+		note(step)
 	}
-	link = purell.NormalizeURL(u, URLNormalisationFlags)
-	if u.Path == "" {
-		return link + "/", true
+	if !allowed {
+		return "", ErrRobotsDisallowed
+	}
+	return link, nil
+}
+
+// robotsAllowed asks RobotsChecker if the URL is allowed to be visited
+func (f *NormalizingFilter) robotsAllowed(u *url.URL) bool {
+	if r, ok := f.robots.(URLRobotsChecker); ok {
+		return r.TestURL(u, f.userAgent)
+	}
+	return f.robots.TestAgent(u.Path, f.userAgent)
+}
+
+// Host returns link host, empty for unparseable links
+func Host(link string) string {
+	if u, err := url.Parse(link); err == nil {
+		return u.Host
 	}
-	return link, true
+	return ""
 }
@@ -1,6 +1,9 @@
 package url_filter
 
 import (
+	"bytes"
+	"log/slog"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -72,8 +75,72 @@ func TestFilter(t *testing.T) {
 	}
 }
 
+func TestFilterURLRobots(t *testing.T) {
+	f := NewFilter("example.com").
+		AllowWWWPrefix(true).
+		WithRobots(&testURLRobot{}, "")
+	_, ok := f.Filter("http://example.com/page?a=1")
+	assert.True(t, ok)
+	_, ok = f.Filter("http://www.example.com/page?a=1")
+	assert.False(t, ok)
+	_, ok = f.Filter("http://example.com/page?fail=1")
+	assert.False(t, ok)
+}
+
+func TestFilterOnReject(t *testing.T) {
+	var rejected []string
+	var reasons []error
+	f := NewFilter("example.com").
+		WithRobots(&testRobot{}, "").
+		OnReject(func(link string, err error) {
+			rejected = append(rejected, link)
+			reasons = append(reasons, err)
+		})
+	_, ok := f.Filter("http://example.com/page")
+	assert.True(t, ok)
+	_, ok = f.Filter("http://example.org/page")
+	assert.False(t, ok)
+	_, ok = f.Filter("http://example.com/fail")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"http://example.org/page", "http://example.com/fail"}, rejected)
+	assert.Equal(t, []error{ErrOutOfScope, ErrRobotsDisallowed}, reasons)
+}
+
+func TestFilterLogger(t *testing.T) {
+	var logs bytes.Buffer
+	f := NewFilter("example.com").
+		WithRobots(&testRobot{}, "").
+		WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+			Level: slog.LevelWarn,
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		})))
+	f.Filter("http://example.org/page")
+	f.Filter("http://example.com/fail")
+	// Scope rejection is logged at debug level
+	assert.Equal(t, "level=WARN msg=\"Link disallowed by robots.txt\" url=http://example.com/fail host=example.com\n", logs.String())
+}
+
 type testRobot struct{}
 
 func (t *testRobot) TestAgent(path, agent string) bool {
 	return !(path == "/fail" || agent == "Failer")
 }
+
+type testURLRobot struct {
+	testRobot
+}
+
+func (t *testURLRobot) TestURL(u *url.URL, agent string) bool {
+	return u.Hostname() == "example.com" && u.RawQuery != "fail=1"
+}
+
+func TestHost(t *testing.T) {
+	assert.Equal(t, "example.com:8080", Host("http://example.com:8080/foo"))
+	assert.Equal(t, "", Host("/foo"))
+	assert.Equal(t, "", Host("http://[::1"))
+}
@@ -0,0 +1,143 @@
+package url_filter
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/purell"
+)
+
+// TrailingSlashPolicy tells what to do with trailing slashes in URL paths
+type TrailingSlashPolicy string
+
+const (
+	TrailingSlashRemove TrailingSlashPolicy = "remove" // /docs/ -> /docs, the default
+	TrailingSlashAdd    TrailingSlashPolicy = "add"    // /docs -> /docs/, paths with file extensions are left as is
+	TrailingSlashKeep   TrailingSlashPolicy = "keep"   // leave paths as they are
+)
+
+// Normalisation defines how links are brought to a canonical form, zero value keeps default behaviour
+type Normalisation struct {
+	// Query and path (;name=value) parameters to remove, names are case-insensitive,
+	// trailing "*" matches any suffix, e.g. "utm_*", "fbclid", "phpsessid", "jsessionid"
+	StripParams []string
+	// Sort query parameters by name
+	SortParams bool
+	// Lowercase paths, for case-insensitive servers
+	LowercasePath bool
+	// Trailing slash handling, TrailingSlashRemove if empty
+	TrailingSlash TrailingSlashPolicy
+	// Remove directory index file names: /docs/index.html -> /docs/
+	CollapseIndex bool
+}
+
+// flags returns purell flags according to normalisation settings
+func (n Normalisation) flags() purell.NormalizationFlags {
+	flags := URLNormalisationFlags
+	if n.SortParams {
+		flags |= purell.FlagSortQuery
+	}
+	if n.CollapseIndex {
+		flags |= purell.FlagRemoveDirectoryIndex
+	}
+	switch n.TrailingSlash {
+	case TrailingSlashAdd, TrailingSlashKeep:
+		flags &^= purell.FlagRemoveTrailingSlash
+	}
+	return flags
+}
+
+// normalise brings the URL into canonical form, trace is called after every step changing the URL
+func (n Normalisation) normalise(u *url.URL, trace func(step, link string)) string {
+	if trace == nil {
+		trace = func(string, string) {}
+	}
+	if len(n.StripParams) > 0 {
+		before := u.String()
+		n.stripQueryParams(u)
+		n.stripPathParams(u)
+		if link := u.String(); link != before {
+			trace("strip parameters", link)
+		}
+	}
+	if n.LowercasePath && u.Path != strings.ToLower(u.Path) {
+		u.Path = strings.ToLower(u.Path)
+		u.RawPath = ""
+		trace("lowercase path", u.String())
+	}
+	if n.TrailingSlash == TrailingSlashAdd && !strings.HasSuffix(u.Path, "/") && path.Ext(u.Path) == "" {
+		u.Path += "/"
+		u.RawPath = ""
+		trace("add trailing slash", u.String())
+	}
+	before := u.String()
+	link := purell.NormalizeURL(u, n.flags())
+	if nu, err := url.Parse(link); err == nil && nu.Path == "" {
+		// Root path is never empty
+		nu.Path = "/"
+		link = nu.String()
+	}
+	if link != before {
+		trace("normalise", link)
+	}
+	return link
+}
+
+// stripParam tells if the parameter is to be removed
+func (n Normalisation) stripParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range n.StripParams {
+		pattern = strings.ToLower(pattern)
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// stripQueryParams removes matching query parameters preserving the order of the rest
+func (n Normalisation) stripQueryParams(u *url.URL) {
+	if u.RawQuery == "" {
+		return
+	}
+	var kept []string
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		name := param
+		if i := strings.Index(param, "="); i >= 0 {
+			name = param[:i]
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !n.stripParam(name) {
+			kept = append(kept, param)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+}
+
+// stripPathParams removes matching ;name=value parameters from path segments
+func (n Normalisation) stripPathParams(u *url.URL) {
+	if !strings.Contains(u.Path, ";") {
+		return
+	}
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		parts := strings.Split(segment, ";")
+		kept := parts[:1]
+		for _, param := range parts[1:] {
+			name := strings.SplitN(param, "=", 2)[0]
+			if !n.stripParam(name) {
+				kept = append(kept, param)
+			}
+		}
+		segments[i] = strings.Join(kept, ";")
+	}
+	u.Path = strings.Join(segments, "/")
+	u.RawPath = ""
+}
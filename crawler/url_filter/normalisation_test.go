@@ -0,0 +1,131 @@
+package url_filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalisation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		n        Normalisation
+		link     string
+		expected string
+	}{
+		{
+			name:     "defaults",
+			link:     "HTTP://example.com:80/Docs/?b=2&a=1#top",
+			expected: "http://example.com/Docs?b=2&a=1",
+		},
+		{
+			name:     "strip params",
+			n:        Normalisation{StripParams: []string{"utm_*", "fbclid", "PHPSESSID"}},
+			link:     "http://example.com/page?utm_source=x&id=5&UTM_Medium=y&fbclid=z&phpsessid=abc&fbclid2=1",
+			expected: "http://example.com/page?id=5&fbclid2=1",
+		},
+		{
+			name:     "strip all params",
+			n:        Normalisation{StripParams: []string{"*"}},
+			link:     "http://example.com/page?a=1&b=2",
+			expected: "http://example.com/page",
+		},
+		{
+			name:     "strip path params",
+			n:        Normalisation{StripParams: []string{"jsessionid"}},
+			link:     "http://example.com/shop;jsessionid=ABC123/cart;jsessionid=DEF;v=1?x=1",
+			expected: "http://example.com/shop/cart;v=1?x=1",
+		},
+		{
+			name:     "sort params",
+			n:        Normalisation{SortParams: true},
+			link:     "http://example.com/?c=3&a=1&b=2",
+			expected: "http://example.com/?a=1&b=2&c=3",
+		},
+		{
+			name:     "lowercase path",
+			n:        Normalisation{LowercasePath: true},
+			link:     "http://example.com/About/Team?Q=A",
+			expected: "http://example.com/about/team?Q=A",
+		},
+		{
+			name:     "add trailing slash",
+			n:        Normalisation{TrailingSlash: TrailingSlashAdd},
+			link:     "http://example.com/docs",
+			expected: "http://example.com/docs/",
+		},
+		{
+			name:     "add trailing slash skips files",
+			n:        Normalisation{TrailingSlash: TrailingSlashAdd},
+			link:     "http://example.com/docs/intro.html",
+			expected: "http://example.com/docs/intro.html",
+		},
+		{
+			name:     "keep trailing slash",
+			n:        Normalisation{TrailingSlash: TrailingSlashKeep},
+			link:     "http://example.com/docs/",
+			expected: "http://example.com/docs/",
+		},
+		{
+			name:     "keep missing trailing slash",
+			n:        Normalisation{TrailingSlash: TrailingSlashKeep},
+			link:     "http://example.com/docs",
+			expected: "http://example.com/docs",
+		},
+		{
+			name:     "collapse index",
+			n:        Normalisation{CollapseIndex: true, TrailingSlash: TrailingSlashKeep},
+			link:     "http://example.com/docs/index.html",
+			expected: "http://example.com/docs/",
+		},
+		{
+			name:     "collapse root index",
+			n:        Normalisation{CollapseIndex: true},
+			link:     "http://example.com/index.php",
+			expected: "http://example.com/",
+		},
+	}
+	for _, tt := range testCases {
+		f := NewFilter("example.com").WithNormalisation(tt.n)
+		normal, ok := f.Filter(tt.link)
+		if assert.True(t, ok, tt.name) {
+			assert.Equal(t, tt.expected, normal, tt.name)
+		}
+	}
+}
+
+func TestNormalisation_RulesSeeNormalisedLink(t *testing.T) {
+	f := NewFilter("example.com").
+		WithNormalisation(Normalisation{LowercasePath: true, StripParams: []string{"sessionid"}}).
+		WithRules(
+			Rule{Action: Exclude, Matcher: QueryParam("sessionid")},
+			Rule{Action: Exclude, Matcher: PathPrefix("/private")},
+		)
+	normal, err := f.Check("http://example.com/page?sessionid=1")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/page", normal)
+	_, err = f.Check("http://example.com/Private/page")
+	assert.Error(t, err)
+}
+
+// prefixRobot disallows paths starting with the prefix, case-sensitively like robots.txt
+type prefixRobot string
+
+func (p prefixRobot) TestAgent(path, _ string) bool {
+	return !strings.HasPrefix(path, string(p))
+}
+
+func TestNormalisation_RobotsSeeLinkedPath(t *testing.T) {
+	f := NewFilter("example.com").
+		WithNormalisation(Normalisation{LowercasePath: true}).
+		WithRobots(prefixRobot("/Private"), "")
+	_, err := f.Check("http://example.com/Private/page")
+	assert.Equal(t, ErrRobotsDisallowed, err)
+	normal, err := f.Check("http://example.com/Public/page")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/public/page", normal)
+	f.WithRobots(prefixRobot("/private"), "")
+	_, err = f.Check("http://example.com/Private/page")
+	assert.Equal(t, ErrRobotsDisallowed, err, "the normalised path is checked as well")
+}
@@ -0,0 +1,153 @@
+package url_filter
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RuleAction tells what to do with links matching a rule
+type RuleAction string
+
+const (
+	Include RuleAction = "include"
+	Exclude RuleAction = "exclude"
+)
+
+// Matcher tells if the URL matches a condition
+type Matcher interface {
+	Match(u *url.URL) bool
+	String() string
+}
+
+// Rule includes or excludes links matching the condition
+type Rule struct {
+	Action  RuleAction
+	Matcher Matcher
+}
+
+func (r Rule) String() string {
+	return string(r.Action) + " " + r.Matcher.String()
+}
+
+// applyRules returns nil if the link is allowed by the rules:
+// the first matching rule decides, links not matching any rule are allowed
+// only when there are no include rules
+func applyRules(rules []Rule, u *url.URL) error {
+	if i := firstMatch(rules, u); i >= 0 {
+		if rules[i].Action == Exclude {
+			return &RuleError{N: i + 1, Rule: rules[i]}
+		}
+		return nil
+	}
+	for i := range rules {
+		if rules[i].Action == Include {
+			return ErrNotIncluded
+		}
+	}
+	return nil
+}
+
+// firstMatch returns index of the first rule matching the URL, -1 if none does
+func firstMatch(rules []Rule, u *url.URL) int {
+	for i := range rules {
+		if rules[i].Matcher.Match(u) {
+			return i
+		}
+	}
+	return -1
+}
+
+type pathPrefix string
+
+// PathPrefix matches URLs with path starting with the prefix
+func PathPrefix(prefix string) Matcher {
+	return pathPrefix(prefix)
+}
+
+func (p pathPrefix) Match(u *url.URL) bool {
+	return strings.HasPrefix(u.Path, string(p))
+}
+
+func (p pathPrefix) String() string {
+	return "path prefix " + string(p)
+}
+
+type globMatcher struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+// Glob matches path and query of the URL against the pattern, where
+// "**" matches any characters and "*" matches any characters except "/";
+// "?" is literal, separating path from query as in URLs
+func Glob(pattern string) (Matcher, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, err
+	}
+	return globMatcher{glob: pattern, re: re}, nil
+}
+
+func (g globMatcher) Match(u *url.URL) bool {
+	target := u.EscapedPath()
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	return g.re.MatchString(target)
+}
+
+func (g globMatcher) String() string {
+	return "glob " + g.glob
+}
+
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+// Regexp matches the whole URL against the regular expression
+func Regexp(expr string) (Matcher, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return regexpMatcher{re: re}, nil
+}
+
+func (r regexpMatcher) Match(u *url.URL) bool {
+	return r.re.MatchString(u.String())
+}
+
+func (r regexpMatcher) String() string {
+	return "regex " + r.re.String()
+}
+
+type queryParam string
+
+// QueryParam matches URLs having the query parameter
+func QueryParam(name string) Matcher {
+	return queryParam(name)
+}
+
+func (q queryParam) Match(u *url.URL) bool {
+	_, ok := u.Query()[string(q)]
+	return ok
+}
+
+func (q queryParam) String() string {
+	return "query parameter " + string(q)
+}
@@ -0,0 +1,90 @@
+package url_filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRules(t *testing.T) {
+	cart, err := Glob("/cart/**")
+	assert.NoError(t, err)
+	search, err := Glob("/search?*")
+	assert.NoError(t, err)
+	pdf, err := Regexp(`\.pdf$`)
+	assert.NoError(t, err)
+	rules := []Rule{
+		{Action: Exclude, Matcher: cart},
+		{Action: Exclude, Matcher: search},
+		{Action: Exclude, Matcher: pdf},
+		{Action: Exclude, Matcher: QueryParam("sessionid")},
+		{Action: Include, Matcher: PathPrefix("/docs/")},
+		{Action: Include, Matcher: PathPrefix("/search")},
+	}
+	f := NewFilter("example.com").WithRules(rules...)
+	testCases := []struct {
+		link     string
+		expected string
+		err      error
+	}{
+		{link: "http://example.com/docs/intro", expected: "http://example.com/docs/intro"},
+		{link: "http://example.com/docs/a/b/c", expected: "http://example.com/docs/a/b/c"},
+		{link: "http://example.com/search", expected: "http://example.com/search"},
+		{link: "http://example.com/", err: ErrNotIncluded},
+		{link: "http://example.com/blog/", err: ErrNotIncluded},
+		{link: "http://example.com/cart/items/1", err: &RuleError{N: 1, Rule: rules[0]}},
+		{link: "http://example.com/search?q=shoes", err: &RuleError{N: 2, Rule: rules[1]}},
+		{link: "http://example.com/docs/manual.pdf", err: &RuleError{N: 3, Rule: rules[2]}},
+		{link: "http://example.com/docs/?sessionid=1", err: &RuleError{N: 4, Rule: rules[3]}},
+		{link: "http://other.com/docs/", err: ErrOutOfScope},
+		{link: string(rune(0x7f)), err: ErrBadURL},
+	}
+	for _, tt := range testCases {
+		normal, err := f.Check(tt.link)
+		assert.Equal(t, tt.err, err, tt.link)
+		assert.Equal(t, tt.expected, normal, tt.link)
+		_, ok := f.Filter(tt.link)
+		assert.Equal(t, tt.err == nil, ok, tt.link)
+	}
+}
+
+func TestRules_ExcludeOnly(t *testing.T) {
+	f := NewFilter("example.com").WithRules(Rule{Action: Exclude, Matcher: PathPrefix("/private")})
+	_, err := f.Check("http://example.com/public")
+	assert.NoError(t, err)
+	_, err = f.Check("http://example.com/private/page")
+	if assert.Error(t, err) {
+		assert.Equal(t, "excluded by rule #1: exclude path prefix /private", err.Error())
+	}
+}
+
+func TestGlob(t *testing.T) {
+	testCases := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{glob: "/docs/*", path: "/docs/intro", match: true},
+		{glob: "/docs/*", path: "/docs/a/b", match: false},
+		{glob: "/docs/**", path: "/docs/a/b", match: true},
+		{glob: "/page-?.html", path: "/page-1.html", match: false},
+		{glob: "/a+b/(c)", path: "/a+b/(c)", match: true},
+		{glob: "/a+b/(c)", path: "/aab/c", match: false},
+		{glob: "/search?*", path: "/search?q=1", match: true},
+		{glob: "/search?*", path: "/searchable", match: false},
+		{glob: "/search?*", path: "/search", match: false},
+	}
+	for _, tt := range testCases {
+		m, err := Glob(tt.glob)
+		if assert.NoError(t, err, tt.glob) {
+			f := NewFilter("").WithRules(Rule{Action: Include, Matcher: m})
+			_, err := f.Check("http://example.com" + tt.path)
+			assert.Equal(t, tt.match, err == nil, tt.glob+" "+tt.path)
+		}
+	}
+}
+
+func TestRegexp_Invalid(t *testing.T) {
+	_, err := Regexp("(")
+	assert.Error(t, err)
+}
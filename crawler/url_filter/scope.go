@@ -0,0 +1,119 @@
+package url_filter
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// HostPolicy tells which hosts relate to the base domain
+type HostPolicy string
+
+const (
+	HostExact      HostPolicy = "exact"      // only the base domain itself
+	HostWWW        HostPolicy = "www"        // base domain with or without "www." prefix
+	HostSubdomains HostPolicy = "subdomains" // base domain and all its subdomains
+)
+
+// SchemePolicy tells which schemes are allowed
+type SchemePolicy string
+
+const (
+	SchemeAny         SchemePolicy = "any"           // both http and https
+	SchemeHTTPSOnly   SchemePolicy = "https_only"    // only https
+	SchemeHTTPToHTTPS SchemePolicy = "http_to_https" // http links on the default port are treated as https ones
+)
+
+// Scope defines which links belong to the crawl
+type Scope struct {
+	// Base domain of the crawl, any host is in scope if empty and there are no allowed hosts
+	BaseDomain string
+	// How hosts are matched against the base domain, HostExact if empty
+	Hosts HostPolicy
+	// Extra hosts in scope, "*.example.com" matches all subdomains of example.com
+	AllowedHosts []string
+	// Allowed schemes, SchemeAny if empty
+	Schemes SchemePolicy
+	// Allowed ports, links without explicit port use 80 for http and 443 for https; any port if empty
+	Ports []int
+}
+
+// check returns nil if the link is in scope, http links may be upgraded to https
+func (s Scope) check(u *url.URL) error {
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+	case "http":
+		switch s.Schemes {
+		case SchemeHTTPSOnly:
+			return ErrSchemeNotAllowed
+		case SchemeHTTPToHTTPS:
+			// Other ports serve plain http, there is nothing to upgrade to
+			if port := u.Port(); port == "" || port == "80" {
+				u.Scheme = "https"
+				u.Host = strings.TrimSuffix(u.Host, ":80")
+			}
+		}
+	default:
+		return ErrSchemeNotAllowed
+	}
+	if !s.hostAllowed(strings.ToLower(u.Hostname())) {
+		return ErrOutOfScope
+	}
+	if !s.portAllowed(u) {
+		return ErrPortNotAllowed
+	}
+	return nil
+}
+
+// hostAllowed tells if the lowercased host is in scope
+func (s Scope) hostAllowed(host string) bool {
+	if s.BaseDomain == "" && len(s.AllowedHosts) == 0 {
+		return true
+	}
+	for _, allowed := range s.AllowedHosts {
+		if matchHost(strings.ToLower(allowed), host) {
+			return true
+		}
+	}
+	if s.BaseDomain == "" {
+		return false
+	}
+	base := strings.ToLower(s.BaseDomain)
+	switch s.Hosts {
+	case HostWWW:
+		return strings.TrimPrefix(host, "www.") == strings.TrimPrefix(base, "www.")
+	case HostSubdomains:
+		base = strings.TrimPrefix(base, "www.")
+		return host == base || strings.HasSuffix(host, "."+base)
+	default:
+		return host == base
+	}
+}
+
+// matchHost matches the host against the pattern, "*.example.com" matches any subdomain of example.com
+func matchHost(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+// portAllowed tells if the link port is in the list of allowed ports
+func (s Scope) portAllowed(u *url.URL) bool {
+	if len(s.Ports) == 0 {
+		return true
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if strings.EqualFold(u.Scheme, "https") {
+			port = "443"
+		}
+	}
+	for _, allowed := range s.Ports {
+		if strconv.Itoa(allowed) == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package url_filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScope(t *testing.T) {
+	testCases := []struct {
+		name     string
+		scope    Scope
+		link     string
+		expected string
+		err      error
+	}{
+		{
+			name:     "exact host, case-insensitive",
+			scope:    Scope{BaseDomain: "Example.com"},
+			link:     "http://EXAMPLE.com/",
+			expected: "http://example.com/",
+		},
+		{
+			name:  "exact host rejects www",
+			scope: Scope{BaseDomain: "example.com", Hosts: HostExact},
+			link:  "http://www.example.com/",
+			err:   ErrOutOfScope,
+		},
+		{
+			name:     "www",
+			scope:    Scope{BaseDomain: "www.example.com", Hosts: HostWWW},
+			link:     "http://example.com/",
+			expected: "http://example.com/",
+		},
+		{
+			name:     "subdomains",
+			scope:    Scope{BaseDomain: "www.example.com", Hosts: HostSubdomains},
+			link:     "http://community.example.com/",
+			expected: "http://community.example.com/",
+		},
+		{
+			name:  "subdomains reject lookalikes",
+			scope: Scope{BaseDomain: "example.com", Hosts: HostSubdomains},
+			link:  "http://badexample.com/",
+			err:   ErrOutOfScope,
+		},
+		{
+			name:     "allowed host",
+			scope:    Scope{BaseDomain: "example.com", AllowedHosts: []string{"blog.example.org"}},
+			link:     "http://blog.example.org/",
+			expected: "http://blog.example.org/",
+		},
+		{
+			name:     "allowed host wildcard",
+			scope:    Scope{AllowedHosts: []string{"*.Example.org"}},
+			link:     "http://a.b.example.org/",
+			expected: "http://a.b.example.org/",
+		},
+		{
+			name:  "allowed host wildcard excludes the domain itself",
+			scope: Scope{AllowedHosts: []string{"*.example.org"}},
+			link:  "http://example.org/",
+			err:   ErrOutOfScope,
+		},
+		{
+			name:  "unsupported scheme",
+			scope: Scope{BaseDomain: "example.com"},
+			link:  "ftp://example.com/",
+			err:   ErrSchemeNotAllowed,
+		},
+		{
+			name:  "mailto",
+			scope: Scope{},
+			link:  "mailto:info@example.com",
+			err:   ErrSchemeNotAllowed,
+		},
+		{
+			name:  "https only",
+			scope: Scope{BaseDomain: "example.com", Schemes: SchemeHTTPSOnly},
+			link:  "http://example.com/",
+			err:   ErrSchemeNotAllowed,
+		},
+		{
+			name:     "http to https",
+			scope:    Scope{BaseDomain: "example.com", Schemes: SchemeHTTPToHTTPS},
+			link:     "http://example.com:80/page",
+			expected: "https://example.com/page",
+		},
+		{
+			name:     "http to https without port",
+			scope:    Scope{BaseDomain: "example.com", Schemes: SchemeHTTPToHTTPS},
+			link:     "http://example.com/page",
+			expected: "https://example.com/page",
+		},
+		{
+			name:     "http to https keeps other ports",
+			scope:    Scope{BaseDomain: "example.com", Schemes: SchemeHTTPToHTTPS},
+			link:     "http://example.com:8080/page",
+			expected: "http://example.com:8080/page",
+		},
+		{
+			name:     "default port allowed",
+			scope:    Scope{BaseDomain: "example.com", Ports: []int{443}},
+			link:     "https://example.com/",
+			expected: "https://example.com/",
+		},
+		{
+			name:  "default port not allowed",
+			scope: Scope{BaseDomain: "example.com", Ports: []int{443}},
+			link:  "http://example.com/",
+			err:   ErrPortNotAllowed,
+		},
+		{
+			name:     "explicit port allowed",
+			scope:    Scope{BaseDomain: "example.com", Ports: []int{8080}},
+			link:     "http://example.com:8080/",
+			expected: "http://example.com:8080/",
+		},
+		{
+			name:     "any port",
+			scope:    Scope{BaseDomain: "example.com"},
+			link:     "http://example.com:8080/",
+			expected: "http://example.com:8080/",
+		},
+	}
+	for _, tt := range testCases {
+		normal, err := NewFilter("").WithScope(tt.scope).Check(tt.link)
+		assert.Equal(t, tt.err, err, tt.name)
+		assert.Equal(t, tt.expected, normal, tt.name)
+	}
+}
@@ -0,0 +1,108 @@
+package visited_set
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+const (
+	// Share of new links taken for visited ones when not set
+	DefaultFalsePositiveRate = 0.001
+	// Links the first filter is sized for
+	initialCapacity = 1 << 16
+	// Every next filter holds this many times more links than the previous one
+	capacityGrowth = 2
+	// and has its false-positive rate multiplied by this, the rates sum up to twice the first one
+	rateTightening = 0.5
+)
+
+// Bloom is a scalable Bloom filter taking a few bytes per link: once a filter is full
+// a larger one with lower false-positive rate is added, so the overall rate stays within the limit;
+// a false positive makes the crawler skip a link it has not visited
+type Bloom struct {
+	filters []*bloomFilter
+}
+
+// bloomFilter is a fixed-size Bloom filter
+type bloomFilter struct {
+	bits     []uint64
+	m        uint64  // number of bits
+	k        uint64  // number of hash functions
+	rate     float64 // false-positive rate once full
+	capacity uint64  // links the filter is sized for
+	n        uint64  // links added
+}
+
+// NewBloom creates empty scalable Bloom filter, falsePositiveRate is between 0 and 1 exclusive
+func NewBloom(falsePositiveRate float64) *Bloom {
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = DefaultFalsePositiveRate
+	}
+	return &Bloom{filters: []*bloomFilter{newBloomFilter(initialCapacity, falsePositiveRate*(1-rateTightening))}}
+}
+
+func newBloomFilter(capacity uint64, rate float64) *bloomFilter {
+	m := uint64(math.Ceil(float64(capacity) * -math.Log(rate) / (math.Ln2 * math.Ln2)))
+	// Rounded up to whole words
+	m = (m + 63) / 64 * 64
+	return &bloomFilter{
+		bits:     make([]uint64, m/64),
+		m:        m,
+		k:        uint64(math.Ceil(-math.Log2(rate))),
+		rate:     rate,
+		capacity: capacity,
+	}
+}
+
+func (b *Bloom) Add(link string) (bool, error) {
+	h1, h2 := hashes(link)
+	for _, f := range b.filters {
+		if f.contains(h1, h2) {
+			return false, nil
+		}
+	}
+	last := b.filters[len(b.filters)-1]
+	if last.n >= last.capacity {
+		last = newBloomFilter(last.capacity*capacityGrowth, last.rate*rateTightening)
+		b.filters = append(b.filters, last)
+	}
+	last.add(h1, h2)
+	return true, nil
+}
+
+// Size returns memory taken by the filters in bytes
+func (b *Bloom) Size() int {
+	size := 0
+	for _, f := range b.filters {
+		size += len(f.bits) * 8
+	}
+	return size
+}
+
+func (f *bloomFilter) add(h1, h2 uint64) {
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.n++
+}
+
+func (f *bloomFilter) contains(h1, h2 uint64) bool {
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashes returns two independent hashes of the link, combined into k ones by double hashing
+func hashes(link string) (uint64, uint64) {
+	h := fnv.New128a()
+	_, _ = h.Write([]byte(link))
+	sum := h.Sum(nil)
+	// Non-zero step keeps the hash functions distinct
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
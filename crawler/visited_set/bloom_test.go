@@ -0,0 +1,37 @@
+package visited_set
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloom(t *testing.T) {
+	assertSet(t, NewBloom(0))
+}
+
+func TestBloom_FalsePositiveRate(t *testing.T) {
+	const n = 300000 // enough for a few filters
+	b := NewBloom(0.01)
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if added, _ := b.Add("http://example.com/page/" + strconv.Itoa(i)); !added {
+			falsePositives++
+		}
+	}
+	assert.Len(t, b.filters, 3)
+	// Every link added is remembered
+	for i := 0; i < n; i += 1000 {
+		added, _ := b.Add("http://example.com/page/" + strconv.Itoa(i))
+		assert.False(t, added)
+	}
+	for i := 0; i < n; i++ {
+		if added, _ := b.Add("http://example.com/other/" + strconv.Itoa(i)); !added {
+			falsePositives++
+		}
+	}
+	assert.Less(t, float64(falsePositives)/(2*n), 0.01)
+	// A few bytes per link
+	assert.Less(t, b.Size(), 4*2*n)
+}
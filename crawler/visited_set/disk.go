@@ -0,0 +1,98 @@
+package visited_set
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"os"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/sqlite_batch"
+	_ "modernc.org/sqlite" // registers "sqlite" database driver
+)
+
+// How many links are added in a single transaction
+const defaultBatchSize = 1000
+
+// Durability is not needed, the set is rebuilt by every crawl
+const diskSchema = `
+PRAGMA journal_mode = OFF;
+PRAGMA synchronous = OFF;
+CREATE TABLE IF NOT EXISTS visited (
+	hash BLOB PRIMARY KEY -- first 16 bytes of link SHA-256
+) WITHOUT ROWID;
+DELETE FROM visited;
+`
+
+// Disk keeps hashes of visited links in SQLite database file, exact with memory use staying flat
+type Disk struct {
+	db        *sql.DB
+	path      string
+	temporary bool // whether the file is removed on close
+	batch     *sqlite_batch.Batch
+}
+
+// OpenDisk opens or creates the database file and clears it, a temporary file is used if path is empty
+func OpenDisk(path string) (*Disk, error) {
+	d := Disk{path: path}
+	if path == "" {
+		f, err := os.CreateTemp("", "visited-*.db")
+		if err != nil {
+			return nil, err
+		}
+		_ = f.Close()
+		d.path, d.temporary = f.Name(), true
+	}
+	db, err := sql.Open("sqlite", d.path)
+	if err == nil {
+		db.SetMaxOpenConns(1)
+		if _, err = db.Exec(diskSchema); err != nil {
+			_ = db.Close()
+		}
+	}
+	if err != nil {
+		if d.temporary {
+			_ = os.Remove(d.path)
+		}
+		return nil, err
+	}
+	d.db, d.batch = db, sqlite_batch.New(db, defaultBatchSize)
+	return &d, nil
+}
+
+// Path returns the database file path
+func (d *Disk) Path() string {
+	return d.path
+}
+
+func (d *Disk) Add(link string) (bool, error) {
+	tx, err := d.batch.Tx()
+	if err != nil {
+		return false, err
+	}
+	hash := sha256.Sum256([]byte(link))
+	res, err := tx.Exec(`INSERT OR IGNORE INTO visited (hash) VALUES (?)`, hash[:16])
+	if err != nil {
+		return false, err
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if err := d.batch.Done(); err != nil {
+		return false, err
+	}
+	return added == 1, nil
+}
+
+// Close closes the database, temporary file is removed
+func (d *Disk) Close() error {
+	err := d.batch.Commit()
+	if closeErr := d.db.Close(); err == nil {
+		err = closeErr
+	}
+	if d.temporary {
+		if removeErr := os.Remove(d.path); err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
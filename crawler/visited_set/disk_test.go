@@ -0,0 +1,48 @@
+package visited_set
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/sqlite_batch"
+)
+
+func TestDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "visited.db")
+	d, err := OpenDisk(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	d.batch = sqlite_batch.New(d.db, 2)
+	assertSet(t, d)
+	for i := 0; i < 5; i++ {
+		added, err := d.Add("http://example.com/" + strconv.Itoa(i))
+		assert.NoError(t, err)
+		assert.True(t, added)
+	}
+	assert.NoError(t, d.Close())
+	// The file is cleared when opened again
+	d, err = OpenDisk(path)
+	if assert.NoError(t, err) {
+		added, err := d.Add("http://example.com/")
+		assert.NoError(t, err)
+		assert.True(t, added)
+		assert.NoError(t, d.Close())
+	}
+	assert.FileExists(t, path)
+}
+
+func TestDisk_Temporary(t *testing.T) {
+	d, err := OpenDisk("")
+	if assert.NoError(t, err) {
+		assertSet(t, d)
+		assert.FileExists(t, d.Path())
+		assert.NoError(t, d.Close())
+		assert.NoFileExists(t, d.Path())
+	}
+	_, err = OpenDisk(filepath.Join(t.TempDir(), "missing", "visited.db"))
+	assert.Error(t, err)
+}
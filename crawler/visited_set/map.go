@@ -0,0 +1,19 @@
+package visited_set
+
+// Map keeps every visited link in memory, exact but growing with the crawl
+type Map struct {
+	links map[string]struct{}
+}
+
+// NewMap creates empty in-memory set
+func NewMap() *Map {
+	return &Map{links: make(map[string]struct{})}
+}
+
+func (m *Map) Add(link string) (bool, error) {
+	if _, ok := m.links[link]; ok {
+		return false, nil
+	}
+	m.links[link] = struct{}{}
+	return true, nil
+}
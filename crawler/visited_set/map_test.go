@@ -0,0 +1,31 @@
+package visited_set
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/types"
+)
+
+var (
+	_ types.VisitedSet = (*Map)(nil)
+	_ types.VisitedSet = (*Bloom)(nil)
+	_ types.VisitedSet = (*Disk)(nil)
+)
+
+// assertSet checks that links are added once
+func assertSet(t *testing.T, set types.VisitedSet) {
+	for _, link := range []string{"http://example.com/", "http://example.com/a"} {
+		added, err := set.Add(link)
+		assert.NoError(t, err)
+		assert.True(t, added, link)
+	}
+	added, err := set.Add("http://example.com/")
+	assert.NoError(t, err)
+	assert.False(t, added)
+}
+
+func TestMap(t *testing.T) {
+	assertSet(t, NewMap())
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dmitry-vovk/wcrawler/crawler/output_sink"
+	"github.com/dmitry-vovk/wcrawler/crawler/text_index"
+)
+
+func TestExplainFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		case "/missing":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+		}
+	}))
+	defer server.Close()
+	testCases := []struct {
+		path           string
+		doHeadRequests bool
+		excluded       bool
+		output         string
+	}{
+		{path: "/page", output: " 3. GET: status 200, Content-Type \"text/html\"\n"},
+		{path: "/missing", output: " 3. GET: status 404, the page is recorded as failed\n"},
+		{path: "/image", excluded: true, output: " 3. GET: rejected, unacceptable content type\nExcluded: unacceptable content type\n"},
+		{path: "/page", doHeadRequests: true, output: " 3. HEAD: status 200, Content-Type \"text/html\"\n"},
+		{path: "/missing", doHeadRequests: true, output: " 3. HEAD: status 404, Content-Type \"text/html\"\n"},
+		{path: "/image", doHeadRequests: true, excluded: true,
+			output: " 3. HEAD: rejected, unacceptable content type (status 200, Content-Type \"image/png\", Content-Length \"\")\n" +
+				"Excluded: unacceptable content type\n"},
+	}
+	for _, tt := range testCases {
+		cfg := defaultConfig()
+		cfg.DoHeadRequests = tt.doHeadRequests
+		fetcher, err := buildFetcher(&cfg, slog.Default())
+		if !assert.NoError(t, err) {
+			return
+		}
+		u, _ := url.Parse(server.URL + tt.path)
+		var out bytes.Buffer
+		assert.Equal(t, tt.excluded, explainFetch(&out, fetcher, tt.doHeadRequests, u, 3), tt.path)
+		assert.Equal(t, tt.output, out.String(), tt.path)
+	}
+}
+
+// interruptingServer serves pages linking to the next ones and cancels the crawl while serving the fifth page
+func interruptingServer(cancel context.CancelFunc, hits *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 5 {
+			cancel()
+		}
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = fmt.Fprintf(w, `<html><body><p>page %d</p><a href="/%d">next</a><a href="/%d">after</a></body></html>`, n, n+1, n+2)
+	}))
+}
+
+func interruptedCrawlConfig(seedURL string) Config {
+	cfg := defaultConfig()
+	cfg.SeedURL = seedURL
+	cfg.IgnoreRobotsTxt = true
+	cfg.MaxPages = 1000
+	cfg.Log.Level = "error"
+	return cfg
+}
+
+func TestRunCrawl_Interrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var hits atomic.Int32
+	server := interruptingServer(cancel, &hits)
+	defer server.Close()
+	dir := t.TempDir()
+	cfg := interruptedCrawlConfig(server.URL + "/0")
+	cfg.Output = OutputConfig{Format: "jsonl", Path: filepath.Join(dir, "results.jsonl.gz"), Gzip: true}
+	cfg.IndexPath = filepath.Join(dir, "index.db")
+	cfg.Visited = VisitedConfig{Store: visitedStoreDisk, Path: filepath.Join(dir, "visited.db")}
+	cfg.NearDuplicateDistance = -1
+	cfg.SkipCanonicalDuplicates = true
+	if !assert.NoError(t, cfg.validate()) {
+		return
+	}
+	assert.Equal(t, 1, runCrawl(ctx, &cfg))
+	assert.Less(t, int(hits.Load()), 10, "no new requests once interrupted")
+
+	// Outputs are complete
+	f, err := os.Open(cfg.Output.Path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if !assert.NoError(t, err) {
+		return
+	}
+	lines := 0
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		lines++
+	}
+	assert.NoError(t, scanner.Err(), "gzip stream is closed properly")
+	assert.GreaterOrEqual(t, lines, 4)
+	index, err := text_index.Open(cfg.IndexPath)
+	if assert.NoError(t, err) {
+		matches, err := index.Search("page")
+		assert.NoError(t, err)
+		assert.Len(t, matches, lines, "every written page is indexed")
+		assert.NoError(t, index.Close())
+	}
+}
+
+func TestRunCrawl_InterruptedRunRecorded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var hits atomic.Int32
+	server := interruptingServer(cancel, &hits)
+	defer server.Close()
+	cfg := interruptedCrawlConfig(server.URL + "/0")
+	cfg.Output = OutputConfig{Format: output_sink.FormatSQLite, Path: filepath.Join(t.TempDir(), "crawl.db")}
+	if !assert.NoError(t, cfg.validate()) {
+		return
+	}
+	assert.Equal(t, 1, runCrawl(ctx, &cfg))
+	db, err := sql.Open("sqlite", cfg.Output.Path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	var status string
+	var finished bool
+	var pages, stored int
+	err = db.QueryRow(`SELECT status, finished_at IS NOT NULL, pages, (SELECT count(*) FROM pages) FROM runs`).
+		Scan(&status, &finished, &pages, &stored)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "interrupted", status)
+		assert.True(t, finished)
+		assert.Positive(t, pages)
+		assert.Equal(t, stored, pages)
+	}
+}